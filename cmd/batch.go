@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/axllent/gitlabci-composer-update-mr/updater"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchFlags = updater.DefaultOptions()
+	batch      = updater.BatchOptions{}
+)
+
+// batchCmd runs the update for multiple projects
+var batchCmd = &cobra.Command{
+	Use:   "batch <commit-user> <commit-email>",
+	Short: "Create composer update merge requests for multiple projects",
+	Long: `Create composer update merge requests for all projects of a GitLab group,
+or a list of projects. Each project is cloned into a temporary directory,
+and its default branch is updated unless --branch is set.
+
+Projects without a composer.lock are skipped.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := updater.DefaultOptions()
+		opts.LoadEnv()
+
+		opts.GitUser = args[0]
+		opts.GitEmail = args[1]
+		opts.GitBranch = batchFlags.GitBranch
+		opts.Runner = runner
+
+		applyFlags(cmd, &opts, batchFlags)
+
+		b := batch
+		b.Options = opts
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		results, err := updater.RunBatch(ctx, b)
+		if err != nil {
+			fmt.Printf("\n==========\nError: %s\n==========\n", err.Error())
+			stop()
+			os.Exit(1)
+		}
+
+		failed := 0
+
+		fmt.Println("\n==========\nBatch results\n==========")
+		for _, r := range results {
+			switch {
+			case r.Err != nil:
+				failed++
+				fmt.Printf("%s: failed: %s\n", r.Project, r.Err.Error())
+			case r.Skipped != "":
+				fmt.Printf("%s: skipped (%s)\n", r.Project, r.Skipped)
+			case r.Result.MergeRequest != nil:
+				fmt.Printf("%s: %s %s\n", r.Project, r.Result.Status, r.Result.MergeRequest.WebURL)
+			default:
+				fmt.Printf("%s: %s\n", r.Project, r.Result.Status)
+			}
+		}
+
+		if failed > 0 {
+			fmt.Printf("\n%d of %d projects failed\n", failed, len(results))
+			stop()
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	addCommonFlags(batchCmd, &batchFlags)
+	batchCmd.Flags().StringVarP(&batchFlags.GitBranch, "branch", "b", "", "Source branch to update (default: each project's default branch)")
+	batchCmd.Flags().StringVarP(&batch.Group, "group", "g", "", "GitLab group to process all projects of")
+	batchCmd.Flags().BoolVar(&batch.IncludeSubgroups, "include-subgroups", false, "Include projects of all subgroups")
+	batchCmd.Flags().StringSliceVar(&batch.Projects, "project", []string{}, "Project path to process (repeatable)")
+	batchCmd.Flags().IntVarP(&batch.Concurrency, "concurrency", "c", 4, "Maximum number of projects processed at the same time")
+	batchCmd.Flags().StringVar(&batch.WorkDir, "work-dir", "", "Directory to clone projects to (default: system temp directory)")
+}
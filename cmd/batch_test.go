@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/axllent/gitlabci-composer-update-mr/internal/fakegitlab"
+	"github.com/axllent/gitlabci-composer-update-mr/updater"
+)
+
+func TestBatchCommand(t *testing.T) {
+	setupEnv(t)
+
+	server := fakegitlab.New(1, "group/project")
+	t.Cleanup(server.Close)
+
+	// clone arguments are not matched as they contain the temporary directory
+	fake := &updater.FakeRunner{Responses: []updater.FakeResponse{
+		{Bin: "git", Do: func(c updater.Command) {
+			_ = os.WriteFile(filepath.Join(c.Args[len(c.Args)-1], "composer.lock"), []byte(lock), 0600)
+		}},
+		{Bin: "git", Args: []string{"checkout", "develop"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}},
+	}}
+	runner = fake
+	t.Cleanup(func() { runner = nil })
+
+	rootCmd.SetArgs([]string{"batch", "bot", "bot@example.com",
+		"--group", "group",
+		"--branch", "develop",
+		"--api-url", server.APIURL(),
+		"--token", "flag-token",
+		"--concurrency", "1",
+	})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", fake.Unused())
+	}
+
+	if len(fake.Calls) == 0 || !hasCall(fake.Calls[:1], "git", "clone", "--quiet", "--branch", "main", "https://gitlab.example.com/group/project.git", fake.Calls[0].Args[len(fake.Calls[0].Args)-1]) {
+		t.Fatalf("expected the project to be cloned, got %v", fake.Calls)
+	}
+
+	// the token flag overrides the environment
+	auth := "GIT_CONFIG_VALUE_0=Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("gitlab-ci-token:flag-token"))
+	if !slices.Contains(fake.Calls[0].Env, auth) {
+		t.Errorf("expected the clone to use the token flag, got %v", fake.Calls[0].Env)
+	}
+}
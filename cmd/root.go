@@ -1,16 +1,30 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/axllent/gitlabci-composer-update-mr/app"
+	"github.com/axllent/gitlabci-composer-update-mr/updater"
 	"github.com/spf13/cobra"
 )
 
+var (
+	flags = updater.DefaultOptions()
+
+	recordFile string
+	replayFile string
+	project    string
+
+	// runner runs the external commands, the updater's default if nil (eg: replaced in tests)
+	runner updater.Runner
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "gitlabci-composer-update-mr <commit-user> <commit-email> <source-branch>",
+	Use:   "gitlabci-composer-update-mr <commit-user> <commit-email> <source-branch[:policy],...>",
 	Short: "A brief description of your application",
 	Long: `A Gitlab CI utility to create composer update merge requests.
 
@@ -19,69 +33,95 @@ Documentation:
 `,
 	Args: cobra.ExactArgs(3),
 	Run: func(cmd *cobra.Command, args []string) {
-		app.Config.GitUser = args[0]
-		app.Config.GitEmail = args[1]
-		app.Config.GitBranch = args[2]
-
-		app.BuildConfig()
-
-		if err := app.SwitchBranch(app.Config.GitBranch); err != nil {
-			fmt.Printf("\n==========\nError switching branch: %s\n==========\n", err.Error())
-			os.Exit(1)
+		opts := buildOptions(cmd, args)
+
+		if recordFile != "" {
+			opts.Runner = &updater.RecordingRunner{
+				Runner:  updater.ExecRunner{},
+				File:    recordFile,
+				Files:   []string{"composer.json", "composer.lock"},
+				Secrets: opts.Secrets(),
+			}
 		}
 
-		preUpdate, err := app.ParseComposerLock()
-		if err != nil {
-			fmt.Printf("\n==========\nError parsing composer.lock: %s\n==========\n", err.Error())
-			os.Exit(1)
+		if replayFile != "" {
+			r, err := updater.NewReplayRunner(replayFile)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			r.Secrets = opts.Secrets()
+			opts.Runner = r
 		}
 
-		if _, err := app.ComposerUpdate(); err != nil {
-			fmt.Printf("\n==========\nError updating with composer: %s\n==========\n", err.Error())
-			os.Exit(1)
-		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-		postUpdate, err := app.ParseComposerLock()
-		if err != nil {
-			fmt.Printf("\n==========\nError parsing composer.lock: %s\n==========\n", err.Error())
+		if _, err := updater.New(opts).Run(ctx); err != nil {
+			fmt.Printf("\n==========\nError: %s\n==========\n", err.Error())
+			stop()
 			os.Exit(1)
 		}
+	},
+}
 
-		// check if composer lock has been modified
-		if preUpdate.Checksum == postUpdate.Checksum {
-			fmt.Println("\n==========\nThere are no updated composer modules\n==========")
-			os.Exit(0)
-		}
-
-		diff := app.CompareDiffs(preUpdate, postUpdate)
+// BuildOptions returns the updater options from the environment,
+// overridden by any flags set on the command line
+func buildOptions(cmd *cobra.Command, args []string) updater.Options {
+	opts := updater.DefaultOptions()
+	opts.LoadEnv()
 
-		if app.MRExists(diff.Checksum) {
-			fmt.Printf("\n==========\nAn identical merge request already exists with checksum: %s\n==========\n", diff.Checksum)
-			os.Exit(0)
-		}
+	opts.GitUser = args[0]
+	opts.GitEmail = args[1]
+	opts.SetBranches(args[2])
+	opts.RepoDir = flags.RepoDir
+	opts.Runner = runner
 
-		if err := app.RemoveOldMRs(); err != nil {
-			fmt.Printf("\n==========\nError removing old merge requests: %s\n==========\n", err.Error())
-			os.Exit(1)
-		}
+	applyFlags(cmd, &opts, flags)
 
-		if err := app.CreateMergeBranch(diff); err != nil {
-			fmt.Printf("\n==========\nError creating merge request: %s\n==========\n", err.Error())
-			os.Exit(1)
-		}
+	if cmd.Flags().Changed("project") {
+		opts.SetProject(project)
+	}
+	if cmd.Flags().Changed("remote") {
+		opts.Remote = flags.Remote
+	}
+	if cmd.Flags().Changed("config") {
+		opts.ConfigFile = flags.ConfigFile
+	}
 
-		packages := "package"
-		if len(diff.Packages) > 0 {
-			packages = "packages"
-		}
+	return opts
+}
 
-		mrTitle := fmt.Sprintf("%s %d %s", app.Config.MRTitlePrefix, len(diff.Packages), packages)
+// ApplyFlags overrides the options with the common flags set on the command line
+func applyFlags(cmd *cobra.Command, opts *updater.Options, f updater.Options) {
+	if cmd.Flags().Changed("composer-flags") {
+		opts.ComposerFlags = f.ComposerFlags
+	}
+	if cmd.Flags().Changed("commit-title") {
+		opts.GitCommitTitle = f.GitCommitTitle
+	}
+	if cmd.Flags().Changed("mr-title-prefix") {
+		opts.MRTitlePrefix = f.MRTitlePrefix
+	}
+	if cmd.Flags().Changed("composer-timeout") {
+		opts.ComposerTimeout = f.ComposerTimeout
+	}
+	if cmd.Flags().Changed("api-url") {
+		opts.APIURL = f.APIURL
+	}
+	if cmd.Flags().Changed("token") {
+		opts.Token = f.Token
+	}
+}
 
-		if err := app.CreateMergeRequest(mrTitle, diff.Description); err != nil {
-			fmt.Printf("\n==========\n%s\n==========\n", err.Error())
-			os.Exit(1)
-		}
-	},
+// AddCommonFlags adds the flags shared by all commands
+func addCommonFlags(cmd *cobra.Command, f *updater.Options) {
+	cmd.Flags().StringSliceVarP(&f.ComposerFlags, "composer-flags", "f", []string{}, "Custom composer flags")
+	cmd.Flags().StringVarP(&f.GitCommitTitle, "commit-title", "t", f.GitCommitTitle, "The git commit message title")
+	cmd.Flags().StringVarP(&f.MRTitlePrefix, "mr-title-prefix", "p", f.MRTitlePrefix, "The merge request title prefix")
+	cmd.Flags().DurationVar(&f.ComposerTimeout, "composer-timeout", 0, "Timeout of each composer command, eg: 10m (default no timeout)")
+	cmd.Flags().StringVar(&f.APIURL, "api-url", "", "GitLab API URL, eg: https://gitlab.example.com/api/v4")
+	cmd.Flags().StringVar(&f.Token, "token", "", "GitLab API token (default $COMPOSER_MR_TOKEN)")
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -94,14 +134,19 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.Flags().StringSliceVarP(&app.Config.ComposerFlags, "composer-flags", "f", []string{}, "Custom composer flags")
-	rootCmd.Flags().StringVarP(&app.Config.RepoDir, "repo", "r", ".", "Repository directory")
-	rootCmd.Flags().StringVarP(&app.Config.GitCommitTitle, "commit-title", "t", "Update composer dependencies", "The git commit message title")
-	rootCmd.Flags().StringVarP(&app.Config.MRTitlePrefix, "mr-title-prefix", "p", "Composer update:", "The merge request title prefix")
-
-	if err := rootCmd.Flags().MarkHidden("repo"); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	addCommonFlags(rootCmd, &flags)
+	rootCmd.Flags().StringVarP(&flags.RepoDir, "repo", "r", ".", "Repository directory")
+	rootCmd.Flags().StringVar(&project, "project", "", "GitLab project path or ID (default: detected from the git remote)")
+	rootCmd.Flags().StringVar(&flags.Remote, "remote", flags.Remote, "Git remote to push to")
+	rootCmd.Flags().StringVar(&flags.ConfigFile, "config", flags.ConfigFile, "Configuration file, relative to the repository")
+	rootCmd.Flags().StringVar(&recordFile, "record", "", "Record all external commands to a fixture file")
+	rootCmd.Flags().StringVar(&replayFile, "replay", "", "Replay external commands from a recorded fixture file")
+
+	for _, f := range []string{"repo", "record", "replay"} {
+		if err := rootCmd.Flags().MarkHidden(f); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	}
 
 	// hide help command
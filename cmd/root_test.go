@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/axllent/gitlabci-composer-update-mr/internal/fakegitlab"
+	"github.com/axllent/gitlabci-composer-update-mr/updater"
+)
+
+// lock is a composer.lock without packages
+const lock = `{"content-hash": "abc", "packages": [], "packages-dev": []}`
+
+// setupEnv isolates the command from the CI environment, sets the API token & title
+// prefix in the environment, and puts a composer-2 binary in the PATH
+func setupEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range []string{"CI_API_V4_URL", "CI_PROJECT_ID", "CI_PROJECT_PATH", "CI_REPOSITORY_URL", "GITLAB_API_PRIVATE_TOKEN", "COMPOSER_MR_PROJECT", "COMPOSER_MR_API_URL"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("COMPOSER_MR_TOKEN", "env-token")
+	t.Setenv("COMPOSER_MR_TITLE_PREFIX", "Env update:")
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "composer-2"), []byte("#!/bin/sh\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// hasCall returns whether a command was run with the arguments
+func hasCall(calls []updater.Command, bin string, args ...string) bool {
+	for _, c := range calls {
+		if filepath.Base(c.Bin) == bin && len(c.Args) == len(args) {
+			match := true
+			for i, arg := range args {
+				match = match && c.Args[i] == arg
+			}
+			if match {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func TestRootCommand(t *testing.T) {
+	setupEnv(t)
+
+	server := fakegitlab.New(1, "group/project")
+	t.Cleanup(server.Close)
+
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "composer.lock"), []byte(lock), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &updater.FakeRunner{Responses: []updater.FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}},
+	}}
+	runner = fake
+	t.Cleanup(func() { runner = nil })
+
+	args := []string{"bot", "bot@example.com", "main"}
+	rootCmd.SetArgs(append(args,
+		"--repo", repo,
+		"--project", "group/project",
+		"--api-url", server.APIURL(),
+		"--token", "flag-token",
+		"--commit-title", "Flag title",
+	))
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", fake.Unused())
+	}
+
+	// the flags override the environment
+	opts := buildOptions(rootCmd, args)
+	if opts.Token != "flag-token" || opts.GitCommitTitle != "Flag title" || opts.MRTitlePrefix != "Env update:" {
+		t.Errorf("unexpected options: token %s, commit title %s, title prefix %s", opts.Token, opts.GitCommitTitle, opts.MRTitlePrefix)
+	}
+
+	if opts.ProjectPath != "group/project" || opts.ProjectID != "" || opts.APIURL != server.APIURL() || opts.RepoDir != repo {
+		t.Errorf("unexpected project: %s %s %s %s", opts.ProjectPath, opts.ProjectID, opts.APIURL, opts.RepoDir)
+	}
+}
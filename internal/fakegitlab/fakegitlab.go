@@ -0,0 +1,829 @@
+// Package fakegitlab is a lightweight in-process GitLab API stand-in for
+// integration testing, implementing only the endpoints used by the application
+package fakegitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Server is a fake GitLab API for a single project
+type Server struct {
+	*httptest.Server
+
+	// ProjectID is the numeric ID of the project
+	ProjectID int
+	// ProjectPath is the full path of the project, eg: group/project
+	ProjectPath string
+
+	// Projects are all projects known to the instance, including the project itself
+	Projects []*gitlab.Project
+	// CurrentUser is the authenticated API user
+	CurrentUser *gitlab.User
+	// Users are all users known to the instance
+	Users []*gitlab.User
+	// Members are the project members, including inherited members
+	Members []*gitlab.ProjectMember
+	// Groups maps group paths to their (inherited) members
+	Groups map[string][]*gitlab.GroupMember
+	// MergeRequests are all merge requests of the project
+	MergeRequests []*gitlab.MergeRequest
+	// Notes are the notes of each merge request, keyed by the merge request IID
+	Notes map[int][]*gitlab.Note
+	// Issues are all issues of the project
+	Issues []*gitlab.Issue
+	// IssueNotes are the notes of each issue, keyed by the issue IID
+	IssueNotes map[int][]*gitlab.Note
+	// Branches are the repository branches
+	Branches map[string]*gitlab.Branch
+	// Commits are the repository commits
+	Commits []*gitlab.Commit
+	// Requests contains all received requests, eg: "GET /projects/1/merge_requests"
+	Requests []string
+
+	mu     sync.Mutex
+	nextID int
+	routes []route
+}
+
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	handler func(w http.ResponseWriter, r *http.Request, params []string)
+}
+
+// New starts a new fake GitLab server for a project. The API is available at URL + "/api/v4".
+func New(projectID int, projectPath string) *Server {
+	s := &Server{
+		ProjectID:   projectID,
+		ProjectPath: projectPath,
+		CurrentUser: &gitlab.User{ID: 1, Username: "composer-bot", Name: "Composer Bot"},
+		Groups:      make(map[string][]*gitlab.GroupMember),
+		Notes:       make(map[int][]*gitlab.Note),
+		IssueNotes:  make(map[int][]*gitlab.Note),
+		Branches:    make(map[string]*gitlab.Branch),
+		nextID:      1000,
+	}
+
+	s.addProject(projectID, projectPath)
+
+	project := `/projects/(?:` + strconv.Itoa(projectID) + `|` + regexp.QuoteMeta(url.PathEscape(projectPath)) + `)`
+
+	s.handle(http.MethodGet, `/user`, s.getCurrentUser)
+	s.handle(http.MethodGet, `/users`, s.listUsers)
+	s.handle(http.MethodGet, `/users/(\d+)`, s.getUser)
+	s.handle(http.MethodGet, `/groups/(.+)/members/all`, s.listGroupMembers)
+	s.handle(http.MethodGet, `/groups/(.+)/projects`, s.listGroupProjects)
+	s.handle(http.MethodGet, `/projects/([^/]+)`, s.getProject)
+	s.handle(http.MethodGet, project+`/members/all`, s.listMembers)
+	s.handle(http.MethodGet, project+`/merge_requests`, s.listMergeRequests)
+	s.handle(http.MethodPost, project+`/merge_requests`, s.createMergeRequest)
+	s.handle(http.MethodGet, project+`/merge_requests/(\d+)`, s.getMergeRequest)
+	s.handle(http.MethodPut, project+`/merge_requests/(\d+)`, s.updateMergeRequest)
+	s.handle(http.MethodGet, project+`/merge_requests/(\d+)/notes`, s.listNotes)
+	s.handle(http.MethodPost, project+`/merge_requests/(\d+)/notes`, s.createNote)
+	s.handle(http.MethodGet, project+`/issues`, s.listIssues)
+	s.handle(http.MethodPost, project+`/issues`, s.createIssue)
+	s.handle(http.MethodPut, project+`/issues/(\d+)`, s.updateIssue)
+	s.handle(http.MethodPost, project+`/issues/(\d+)/notes`, s.createIssueNote)
+	s.handle(http.MethodGet, project+`/repository/branches`, s.listBranches)
+	s.handle(http.MethodGet, project+`/repository/branches/(.+)`, s.getBranch)
+	s.handle(http.MethodDelete, project+`/repository/branches/(.+)`, s.deleteBranch)
+	s.handle(http.MethodGet, project+`/repository/commits`, s.listCommits)
+
+	s.Server = httptest.NewServer(s)
+
+	return s
+}
+
+// APIURL returns the API base URL, eg for CI_API_V4_URL
+func (s *Server) APIURL() string {
+	return s.URL + "/api/v4"
+}
+
+// AddUser adds a user to the instance, and optionally as a project member
+func (s *Server) AddUser(id int, username, email string, member bool) *gitlab.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := &gitlab.User{ID: id, Username: username, Email: email, PublicEmail: email, Name: username}
+	s.Users = append(s.Users, u)
+
+	if member {
+		s.Members = append(s.Members, &gitlab.ProjectMember{ID: id, Username: username, Name: username})
+	}
+
+	return u
+}
+
+// AddProject adds another project to the instance. Only the project itself
+// supports merge requests, branches etc.
+func (s *Server) AddProject(id int, path string) *gitlab.Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addProject(id, path)
+}
+
+// AddGroup adds a group with the given member user IDs
+func (s *Server) AddGroup(path string, ids ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		for _, u := range s.Users {
+			if u.ID == id {
+				s.Groups[path] = append(s.Groups[path], &gitlab.GroupMember{ID: u.ID, Username: u.Username})
+			}
+		}
+	}
+}
+
+// AddMergeRequest adds an existing merge request, setting defaults for any unset fields
+func (s *Server) AddMergeRequest(mr *gitlab.MergeRequest) *gitlab.MergeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addMergeRequest(mr)
+
+	return mr
+}
+
+// MergeRequest returns a merge request by its IID
+func (s *Server) MergeRequest(iid int) *gitlab.MergeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.findMergeRequest(iid)
+}
+
+// AddIssue adds an existing issue, setting defaults for any unset fields
+func (s *Server) AddIssue(issue *gitlab.Issue) *gitlab.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addIssue(issue)
+
+	return issue
+}
+
+// OpenIssues returns all open issues
+func (s *Server) OpenIssues() []*gitlab.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issues := []*gitlab.Issue{}
+	for _, issue := range s.Issues {
+		if issue.State == "opened" {
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues
+}
+
+// OpenMergeRequests returns all open merge requests
+func (s *Server) OpenMergeRequests() []*gitlab.MergeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mrs := []*gitlab.MergeRequest{}
+	for _, mr := range s.MergeRequests {
+		if mr.State == "opened" {
+			mrs = append(mrs, mr)
+		}
+	}
+
+	return mrs
+}
+
+// ServeHTTP routes API requests
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4")
+
+	s.Requests = append(s.Requests, r.Method+" "+path)
+
+	for _, rt := range s.routes {
+		if rt.method != r.Method {
+			continue
+		}
+		if params := rt.pattern.FindStringSubmatch(path); params != nil {
+			for i, p := range params {
+				params[i], _ = url.PathUnescape(p)
+			}
+			rt.handler(w, r, params[1:])
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "404 Not Found")
+}
+
+func (s *Server) handle(method, pattern string, handler func(w http.ResponseWriter, r *http.Request, params []string)) {
+	s.routes = append(s.routes, route{method: method, pattern: regexp.MustCompile(`^` + pattern + `$`), handler: handler})
+}
+
+func (s *Server) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Server) addProject(id int, path string) *gitlab.Project {
+	namespace := ""
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		namespace = path[:i]
+	}
+
+	p := &gitlab.Project{
+		ID:                       id,
+		Path:                     path[strings.LastIndex(path, "/")+1:],
+		PathWithNamespace:        path,
+		DefaultBranch:            "main",
+		HTTPURLToRepo:            "https://gitlab.example.com/" + path + ".git",
+		MergeRequestsAccessLevel: gitlab.EnabledAccessControl,
+		Namespace:                &gitlab.ProjectNamespace{FullPath: namespace},
+	}
+	s.Projects = append(s.Projects, p)
+
+	return p
+}
+
+func (s *Server) addMergeRequest(mr *gitlab.MergeRequest) {
+	if mr.ID == 0 {
+		mr.ID = s.id()
+	}
+	if mr.IID == 0 {
+		mr.IID = len(s.MergeRequests) + 1
+	}
+	if mr.State == "" {
+		mr.State = "opened"
+	}
+	if mr.Author == nil {
+		mr.Author = s.basicUser(s.CurrentUser.ID)
+	}
+	if mr.CreatedAt == nil {
+		created := time.Now().Add(time.Duration(mr.IID) * time.Second)
+		mr.CreatedAt = &created
+	}
+	if mr.Labels == nil {
+		mr.Labels = gitlab.Labels{}
+	}
+	if mr.WebURL == "" {
+		mr.WebURL = fmt.Sprintf("%s/%s/-/merge_requests/%d", s.URL, s.ProjectPath, mr.IID)
+	}
+	if mr.MergeStatus == "" {
+		mr.MergeStatus = "can_be_merged"
+	}
+	mr.ProjectID = s.ProjectID
+
+	if _, ok := s.Branches[mr.SourceBranch]; !ok && mr.SourceBranch != "" {
+		s.Branches[mr.SourceBranch] = &gitlab.Branch{Name: mr.SourceBranch}
+	}
+
+	s.MergeRequests = append(s.MergeRequests, mr)
+}
+
+func (s *Server) findMergeRequest(iid int) *gitlab.MergeRequest {
+	for _, mr := range s.MergeRequests {
+		if mr.IID == iid {
+			return mr
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) basicUser(id int) *gitlab.BasicUser {
+	users := append([]*gitlab.User{s.CurrentUser}, s.Users...)
+	for _, u := range users {
+		if u.ID == id {
+			return &gitlab.BasicUser{ID: u.ID, Username: u.Username, Name: u.Name}
+		}
+	}
+
+	return &gitlab.BasicUser{ID: id}
+}
+
+func (s *Server) basicUsers(ids *[]int) []*gitlab.BasicUser {
+	users := []*gitlab.BasicUser{}
+	if ids == nil {
+		return users
+	}
+	for _, id := range *ids {
+		users = append(users, s.basicUser(id))
+	}
+
+	return users
+}
+
+func (s *Server) getCurrentUser(w http.ResponseWriter, _ *http.Request, _ []string) {
+	writeJSON(w, http.StatusOK, s.CurrentUser)
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request, _ []string) {
+	q := r.URL.Query()
+	users := []*gitlab.User{}
+	for _, u := range s.Users {
+		if username := q.Get("username"); username != "" && !strings.EqualFold(u.Username, username) {
+			continue
+		}
+		// the search matches part of the name, username or public email
+		if search := strings.ToLower(q.Get("search")); search != "" && !strings.Contains(strings.ToLower(u.Name), search) &&
+			!strings.Contains(strings.ToLower(u.Username), search) && !strings.Contains(strings.ToLower(u.PublicEmail), search) {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	writePage(w, r, users)
+}
+
+func (s *Server) getUser(w http.ResponseWriter, _ *http.Request, params []string) {
+	id, _ := strconv.Atoi(params[0])
+	for _, u := range s.Users {
+		if u.ID == id {
+			writeJSON(w, http.StatusOK, u)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "404 User Not Found")
+}
+
+func (s *Server) listGroupMembers(w http.ResponseWriter, r *http.Request, params []string) {
+	members, ok := s.Groups[params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404 Group Not Found")
+		return
+	}
+
+	writePage(w, r, members)
+}
+
+func (s *Server) listGroupProjects(w http.ResponseWriter, r *http.Request, params []string) {
+	subgroups := r.URL.Query().Get("include_subgroups") == "true"
+
+	projects := []*gitlab.Project{}
+	for _, p := range s.Projects {
+		ns := p.Namespace.FullPath
+		if ns == params[0] || (subgroups && strings.HasPrefix(ns, params[0]+"/")) {
+			projects = append(projects, p)
+		}
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].PathWithNamespace < projects[j].PathWithNamespace
+	})
+
+	writePage(w, r, projects)
+}
+
+func (s *Server) getProject(w http.ResponseWriter, _ *http.Request, params []string) {
+	for _, p := range s.Projects {
+		if strconv.Itoa(p.ID) == params[0] || p.PathWithNamespace == params[0] {
+			writeJSON(w, http.StatusOK, p)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "404 Project Not Found")
+}
+
+func (s *Server) listMembers(w http.ResponseWriter, r *http.Request, _ []string) {
+	writePage(w, r, s.Members)
+}
+
+func (s *Server) listMergeRequests(w http.ResponseWriter, r *http.Request, _ []string) {
+	q := r.URL.Query()
+
+	mrs := []*gitlab.MergeRequest{}
+	for _, mr := range s.MergeRequests {
+		if state := q.Get("state"); state != "" && state != "all" && mr.State != state {
+			continue
+		}
+		if target := q.Get("target_branch"); target != "" && mr.TargetBranch != target {
+			continue
+		}
+		if source := q.Get("source_branch"); source != "" && mr.SourceBranch != source {
+			continue
+		}
+		if author := q.Get("author_id"); author != "" && (mr.Author == nil || strconv.Itoa(mr.Author.ID) != author) {
+			continue
+		}
+		if labels := q.Get("labels"); labels != "" && !hasLabels(mr.Labels, strings.Split(labels, ",")) {
+			continue
+		}
+		if search := q.Get("search"); search != "" && !strings.Contains(mr.Title, search) && !strings.Contains(mr.Description, search) {
+			continue
+		}
+		mrs = append(mrs, mr)
+	}
+
+	// GitLab orders by created_at desc by default
+	sort.SliceStable(mrs, func(i, j int) bool {
+		if q.Get("sort") == "asc" {
+			return mrs[i].CreatedAt.Before(*mrs[j].CreatedAt)
+		}
+		return mrs[i].CreatedAt.After(*mrs[j].CreatedAt)
+	})
+
+	writePage(w, r, mrs)
+}
+
+func (s *Server) createMergeRequest(w http.ResponseWriter, r *http.Request, _ []string) {
+	opts := gitlab.CreateMergeRequestOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.Title == nil || opts.SourceBranch == nil || opts.TargetBranch == nil {
+		writeError(w, http.StatusBadRequest, "title, source_branch and target_branch are required")
+		return
+	}
+
+	for _, mr := range s.MergeRequests {
+		if mr.State == "opened" && mr.SourceBranch == *opts.SourceBranch && mr.TargetBranch == *opts.TargetBranch {
+			writeError(w, http.StatusConflict, "Another open merge request already exists for this source branch")
+			return
+		}
+	}
+
+	mr := &gitlab.MergeRequest{
+		Title:        *opts.Title,
+		SourceBranch: *opts.SourceBranch,
+		TargetBranch: *opts.TargetBranch,
+		Assignees:    s.basicUsers(opts.AssigneeIDs),
+		Reviewers:    s.basicUsers(opts.ReviewerIDs),
+	}
+	if opts.Description != nil {
+		mr.Description = *opts.Description
+	}
+	if opts.Labels != nil {
+		mr.Labels = gitlab.Labels(*opts.Labels)
+	}
+	if opts.RemoveSourceBranch != nil {
+		mr.ForceRemoveSourceBranch = *opts.RemoveSourceBranch
+	}
+	mr.Draft = strings.HasPrefix(mr.Title, "Draft:")
+
+	s.addMergeRequest(mr)
+
+	writeJSON(w, http.StatusCreated, mr)
+}
+
+func (s *Server) getMergeRequest(w http.ResponseWriter, _ *http.Request, params []string) {
+	iid, _ := strconv.Atoi(params[0])
+	mr := s.findMergeRequest(iid)
+	if mr == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, mr)
+}
+
+func (s *Server) updateMergeRequest(w http.ResponseWriter, r *http.Request, params []string) {
+	iid, _ := strconv.Atoi(params[0])
+	mr := s.findMergeRequest(iid)
+	if mr == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+
+	opts := gitlab.UpdateMergeRequestOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.Title != nil {
+		mr.Title = *opts.Title
+		mr.Draft = strings.HasPrefix(mr.Title, "Draft:")
+	}
+	if opts.Description != nil {
+		mr.Description = *opts.Description
+	}
+	if opts.Labels != nil {
+		mr.Labels = gitlab.Labels(*opts.Labels)
+	}
+	if opts.AddLabels != nil {
+		for _, l := range *opts.AddLabels {
+			if !hasLabels(mr.Labels, []string{l}) {
+				mr.Labels = append(mr.Labels, l)
+			}
+		}
+	}
+	if opts.RemoveLabels != nil {
+		labels := gitlab.Labels{}
+		for _, l := range mr.Labels {
+			if !hasLabels(gitlab.Labels(*opts.RemoveLabels), []string{l}) {
+				labels = append(labels, l)
+			}
+		}
+		mr.Labels = labels
+	}
+	if opts.AssigneeIDs != nil {
+		mr.Assignees = s.basicUsers(opts.AssigneeIDs)
+	}
+	if opts.ReviewerIDs != nil {
+		mr.Reviewers = s.basicUsers(opts.ReviewerIDs)
+	}
+	if opts.StateEvent != nil {
+		switch *opts.StateEvent {
+		case "close":
+			mr.State = "closed"
+		case "reopen":
+			mr.State = "opened"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, mr)
+}
+
+func (s *Server) listNotes(w http.ResponseWriter, r *http.Request, params []string) {
+	iid, _ := strconv.Atoi(params[0])
+	if s.findMergeRequest(iid) == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+
+	notes := s.Notes[iid]
+	if notes == nil {
+		notes = []*gitlab.Note{}
+	}
+
+	writePage(w, r, notes)
+}
+
+func (s *Server) createNote(w http.ResponseWriter, r *http.Request, params []string) {
+	iid, _ := strconv.Atoi(params[0])
+	if s.findMergeRequest(iid) == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+
+	opts := gitlab.CreateMergeRequestNoteOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil || opts.Body == nil {
+		writeError(w, http.StatusBadRequest, "body is required")
+		return
+	}
+
+	note := &gitlab.Note{ID: s.id(), Body: *opts.Body, NoteableIID: iid, NoteableType: "MergeRequest"}
+	note.Author.ID = s.CurrentUser.ID
+	note.Author.Username = s.CurrentUser.Username
+
+	s.Notes[iid] = append(s.Notes[iid], note)
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func (s *Server) addIssue(issue *gitlab.Issue) {
+	if issue.ID == 0 {
+		issue.ID = s.id()
+	}
+	if issue.IID == 0 {
+		issue.IID = len(s.Issues) + 1
+	}
+	if issue.State == "" {
+		issue.State = "opened"
+	}
+	if issue.Author == nil {
+		issue.Author = &gitlab.IssueAuthor{ID: s.CurrentUser.ID, Username: s.CurrentUser.Username}
+	}
+	if issue.WebURL == "" {
+		issue.WebURL = fmt.Sprintf("%s/%s/-/issues/%d", s.URL, s.ProjectPath, issue.IID)
+	}
+
+	s.Issues = append(s.Issues, issue)
+}
+
+func (s *Server) findIssue(iid int) *gitlab.Issue {
+	for _, issue := range s.Issues {
+		if issue.IID == iid {
+			return issue
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) listIssues(w http.ResponseWriter, r *http.Request, _ []string) {
+	q := r.URL.Query()
+
+	issues := []*gitlab.Issue{}
+	for _, issue := range s.Issues {
+		if state := q.Get("state"); state != "" && state != "all" && issue.State != state {
+			continue
+		}
+		if author := q.Get("author_id"); author != "" && (issue.Author == nil || strconv.Itoa(issue.Author.ID) != author) {
+			continue
+		}
+		if labels := q.Get("labels"); labels != "" && !hasLabels(issue.Labels, strings.Split(labels, ",")) {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+
+	writePage(w, r, issues)
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, r *http.Request, _ []string) {
+	opts := gitlab.CreateIssueOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil || opts.Title == nil {
+		writeError(w, http.StatusBadRequest, "title is required")
+		return
+	}
+
+	issue := &gitlab.Issue{Title: *opts.Title}
+	if opts.Description != nil {
+		issue.Description = *opts.Description
+	}
+	if opts.Labels != nil {
+		issue.Labels = gitlab.Labels(*opts.Labels)
+	}
+
+	s.addIssue(issue)
+
+	writeJSON(w, http.StatusCreated, issue)
+}
+
+func (s *Server) updateIssue(w http.ResponseWriter, r *http.Request, params []string) {
+	iid, _ := strconv.Atoi(params[0])
+	issue := s.findIssue(iid)
+	if issue == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+
+	opts := gitlab.UpdateIssueOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.Title != nil {
+		issue.Title = *opts.Title
+	}
+	if opts.Description != nil {
+		issue.Description = *opts.Description
+	}
+	if opts.Labels != nil {
+		issue.Labels = gitlab.Labels(*opts.Labels)
+	}
+	if opts.StateEvent != nil {
+		switch *opts.StateEvent {
+		case "close":
+			issue.State = "closed"
+		case "reopen":
+			issue.State = "opened"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, issue)
+}
+
+func (s *Server) createIssueNote(w http.ResponseWriter, r *http.Request, params []string) {
+	iid, _ := strconv.Atoi(params[0])
+	if s.findIssue(iid) == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+
+	opts := gitlab.CreateIssueNoteOptions{}
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil || opts.Body == nil {
+		writeError(w, http.StatusBadRequest, "body is required")
+		return
+	}
+
+	note := &gitlab.Note{ID: s.id(), Body: *opts.Body, NoteableIID: iid, NoteableType: "Issue"}
+	note.Author.ID = s.CurrentUser.ID
+	note.Author.Username = s.CurrentUser.Username
+
+	s.IssueNotes[iid] = append(s.IssueNotes[iid], note)
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func (s *Server) listBranches(w http.ResponseWriter, r *http.Request, _ []string) {
+	names := []string{}
+	for name := range s.Branches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	branches := []*gitlab.Branch{}
+	for _, name := range names {
+		branches = append(branches, s.Branches[name])
+	}
+
+	writePage(w, r, branches)
+}
+
+func (s *Server) getBranch(w http.ResponseWriter, _ *http.Request, params []string) {
+	branch, ok := s.Branches[params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, branch)
+}
+
+func (s *Server) deleteBranch(w http.ResponseWriter, _ *http.Request, params []string) {
+	if _, ok := s.Branches[params[0]]; !ok {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+
+	delete(s.Branches, params[0])
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listCommits(w http.ResponseWriter, r *http.Request, _ []string) {
+	commits := s.Commits
+	if commits == nil {
+		commits = []*gitlab.Commit{}
+	}
+
+	writePage(w, r, commits)
+}
+
+// hasLabels returns whether all labels are set (case-insensitive)
+func hasLabels(labels gitlab.Labels, required []string) bool {
+	for _, req := range required {
+		found := false
+		for _, l := range labels {
+			if strings.EqualFold(l, strings.TrimSpace(req)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writePage writes a single page of results, setting the GitLab pagination headers
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = 20
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+
+	start := (page - 1) * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+
+	totalPages := (len(items) + perPage - 1) / perPage
+
+	w.Header().Set("X-Page", strconv.Itoa(page))
+	w.Header().Set("X-Per-Page", strconv.Itoa(perPage))
+	w.Header().Set("X-Total", strconv.Itoa(len(items)))
+	w.Header().Set("X-Total-Pages", strconv.Itoa(totalPages))
+	if page < totalPages {
+		w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	}
+
+	writeJSON(w, http.StatusOK, items[start:end])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
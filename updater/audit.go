@@ -0,0 +1,116 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Advisory is a security advisory reported by composer audit
+type Advisory struct {
+	AdvisoryID       string `json:"advisoryId"`
+	PackageName      string `json:"packageName"`
+	AffectedVersions string `json:"affectedVersions"`
+	Title            string `json:"title"`
+	CVE              string `json:"cve"`
+	Link             string `json:"link"`
+}
+
+// UpdateType is the kind of a package update, in order of priority
+type UpdateType int
+
+const (
+	// UpdateSecurity is an update of a package with a security advisory
+	UpdateSecurity UpdateType = iota
+	// UpdatePatch is a patch version update
+	UpdatePatch
+	// UpdateMinor is a minor version update
+	UpdateMinor
+	// UpdateMajor is a major version update, or an unknown (eg: branch) update
+	UpdateMajor
+)
+
+// String returns the name of the update type
+func (t UpdateType) String() string {
+	return [...]string{"security", "patch", "minor", "major"}[t]
+}
+
+// Audit returns the security advisories of the locked packages by package name
+func (u *Updater) audit() (map[string][]Advisory, error) {
+	if u.opts.ComposerVersion == 1 {
+		return nil, fmt.Errorf("composer audit requires composer 2")
+	}
+
+	// composer audit exits with an error when advisories are found
+	out, err := u.composer("audit", "--locked", "--format=json", "--no-interaction")
+
+	report := struct {
+		// Advisories is an empty array when there are no advisories
+		Advisories json.RawMessage `json:"advisories"`
+	}{}
+
+	start, end := strings.Index(out, "{"), strings.LastIndex(out, "}")
+	if start < 0 || end < start {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid composer audit output")
+	}
+
+	if jsonErr := json.Unmarshal([]byte(out[start:end+1]), &report); jsonErr != nil {
+		return nil, fmt.Errorf("invalid composer audit output: %w", jsonErr)
+	}
+
+	advisories := map[string][]Advisory{}
+	if strings.HasPrefix(strings.TrimSpace(string(report.Advisories)), "{") {
+		if err := json.Unmarshal(report.Advisories, &advisories); err != nil {
+			return nil, fmt.Errorf("invalid composer audit output: %w", err)
+		}
+	}
+
+	return advisories, nil
+}
+
+// UpdateTypeOf returns the kind of a package update. Added & removed packages
+// are dependency changes, and are reported as patch updates.
+func updateTypeOf(p ComposerDiffPackage, advisories map[string][]Advisory) UpdateType {
+	if len(advisories[p.Name]) > 0 {
+		return UpdateSecurity
+	}
+
+	if p.PreVersion == "" || p.PostVersion == "" {
+		return UpdatePatch
+	}
+
+	pre, _, err := parseVersion(p.PreVersion)
+	if err != nil {
+		return UpdateMajor
+	}
+
+	post, _, err := parseVersion(p.PostVersion)
+	if err != nil {
+		return UpdateMajor
+	}
+
+	switch {
+	// 0.x minor versions are considered major by composer
+	case pre[0] != post[0] || (pre[0] == 0 && pre[1] != post[1]):
+		return UpdateMajor
+	case pre[1] != post[1]:
+		return UpdateMinor
+	default:
+		return UpdatePatch
+	}
+}
+
+// UpdatePriority returns the highest priority update type of the packages
+func updatePriority(packages []ComposerDiffPackage, advisories map[string][]Advisory) UpdateType {
+	priority := UpdateMajor
+	for _, p := range packages {
+		if t := updateTypeOf(p, advisories); t < priority {
+			priority = t
+		}
+	}
+
+	return priority
+}
@@ -0,0 +1,265 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// BatchOptions configures a batch run over multiple projects
+type BatchOptions struct {
+	// Options are the base options for every project. The project ID, path,
+	// repository URL & directory are set per project, and each project's
+	// repository configuration file is applied on top.
+	Options Options
+
+	// Group is a GitLab group (path or ID) to process all projects of
+	Group string
+
+	// IncludeSubgroups includes the projects of all subgroups of Group
+	IncludeSubgroups bool
+
+	// Projects are project paths (or IDs) to process
+	Projects []string
+
+	// Concurrency is the maximum number of projects processed at the same time
+	Concurrency int
+
+	// WorkDir is where the projects are cloned to, defaults to the system temp directory
+	WorkDir string
+}
+
+// ProjectResult is the result of a single project of a batch run
+type ProjectResult struct {
+	// Project is the project path
+	Project string
+	// Result is the update result
+	Result Result
+	// Skipped is the reason the project was skipped, if skipped
+	Skipped string
+	// Err is the error of the update run, if failed
+	Err error
+	// Output is all progress output of the project
+	Output string
+}
+
+// RunBatch clones each project of a group (or list of projects) into a
+// temporary directory & runs the update flow, returning the results in the
+// same order as the projects. Project failures do not abort the batch.
+func RunBatch(ctx context.Context, b BatchOptions) ([]ProjectResult, error) {
+	if b.Options.Token == "" || b.Options.APIURL == "" {
+		return nil, fmt.Errorf("gitlab API URL & token not set")
+	}
+
+	if b.Group == "" && len(b.Projects) == 0 {
+		return nil, fmt.Errorf("no group or projects set")
+	}
+
+	if b.Options.Runner == nil {
+		b.Options.Runner = ExecRunner{}
+	}
+
+	if b.Options.Output == nil {
+		b.Options.Output = os.Stdout
+	}
+
+	if b.Options.GitPath == "" {
+		gitPath, err := which("git")
+		if err != nil {
+			return nil, fmt.Errorf("\"git\" not found")
+		}
+		b.Options.GitPath = gitPath
+	}
+
+	if b.Concurrency < 1 {
+		b.Concurrency = 1
+	}
+
+	client, err := gitlab.NewClient(b.Options.Token,
+		gitlab.WithBaseURL(b.Options.APIURL),
+		gitlab.WithRequestOptions(gitlab.WithContext(ctx)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %v", err)
+	}
+
+	projects, err := batchProjects(client, b)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ProjectResult, len(projects))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, b.Concurrency)
+
+	for i, p := range projects {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = ProjectResult{Project: p.PathWithNamespace, Err: ctx.Err()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, p *gitlab.Project) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = runProject(ctx, b, p)
+
+			// print each project's output once complete to avoid interleaving
+			mu.Lock()
+			fmt.Fprintf(b.Options.Output, "\n==========\nProject: %s\n==========\n%s", p.PathWithNamespace, results[i].Output)
+			mu.Unlock()
+		}(i, p)
+	}
+
+	wg.Wait()
+
+	return results, nil
+}
+
+// BatchProjects returns all the projects to process, skipping archived
+// projects & projects without merge requests
+func batchProjects(client *gitlab.Client, b BatchOptions) ([]*gitlab.Project, error) {
+	projects := []*gitlab.Project{}
+	seen := make(map[int]bool)
+
+	add := func(p *gitlab.Project) {
+		if seen[p.ID] || p.Archived || p.MergeRequestsAccessLevel == gitlab.DisabledAccessControl {
+			return
+		}
+		seen[p.ID] = true
+		projects = append(projects, p)
+	}
+
+	if b.Group != "" {
+		opts := &gitlab.ListGroupProjectsOptions{
+			ListOptions:      gitlab.ListOptions{PerPage: 100},
+			Archived:         gitlab.Ptr(false),
+			IncludeSubGroups: gitlab.Ptr(b.IncludeSubgroups),
+			OrderBy:          gitlab.Ptr("path"),
+			Sort:             gitlab.Ptr("asc"),
+		}
+
+		for {
+			list, resp, err := client.Groups.ListGroupProjects(b.Group, opts)
+			if err != nil {
+				return nil, fmt.Errorf("listing projects of group %s: %w", b.Group, err)
+			}
+
+			for _, p := range list {
+				add(p)
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+
+	for _, path := range b.Projects {
+		var pid interface{} = path
+		if id, err := strconv.Atoi(path); err == nil {
+			pid = id
+		}
+
+		p, _, err := client.Projects.GetProject(pid, nil)
+		if err != nil {
+			return nil, fmt.Errorf("getting project %s: %w", path, err)
+		}
+
+		add(p)
+	}
+
+	return projects, nil
+}
+
+// RunProject clones a project & runs the update flow in the clone
+func runProject(ctx context.Context, b BatchOptions, p *gitlab.Project) ProjectResult {
+	pr := ProjectResult{Project: p.PathWithNamespace}
+
+	var output bytes.Buffer
+	defer func() { pr.Output = output.String() }()
+
+	if u, err := url.Parse(p.HTTPURLToRepo); err != nil || u.Host == "" {
+		pr.Err = fmt.Errorf("invalid repository URL \"%s\"", p.HTTPURLToRepo)
+		return pr
+	}
+
+	// the token is passed to git in the environment, as the commands of the
+	// repository configuration could read it from the remote URL in .git/config
+	env := gitAuthEnv(b.Options.Token)
+
+	dir, err := os.MkdirTemp(b.WorkDir, "composer-update-mr-")
+	if err != nil {
+		pr.Err = err
+		return pr
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"clone", "--quiet"}
+	if p.DefaultBranch != "" {
+		args = append(args, "--branch", p.DefaultBranch)
+	}
+	args = append(args, p.HTTPURLToRepo, dir)
+
+	if out, err := b.Options.Runner.Run(ctx, Command{Dir: b.WorkDir, Bin: b.Options.GitPath, Args: args, Env: env, Quiet: true}); err != nil {
+		output.WriteString(mask(out, b.Options.Secrets()))
+		pr.Err = fmt.Errorf("cloning %s: %w", p.PathWithNamespace, err)
+		return pr
+	}
+
+	if !isFile(filepath.Join(dir, "composer.lock")) {
+		pr.Skipped = "no composer.lock"
+		return pr
+	}
+
+	opts := b.Options
+	opts.RepoDir = dir
+	opts.ProjectID = strconv.Itoa(p.ID)
+	opts.ProjectPath = p.PathWithNamespace
+	opts.RepositoryURL = ""
+	opts.gitEnv = env
+	opts.Output = &output
+	opts.untrustedCommands = true
+	if opts.GitBranch == "" {
+		opts.GitBranch = p.DefaultBranch
+	}
+
+	pr.Result, pr.Err = New(opts).Run(ctx)
+	if pr.Result.Status == StatusDisabled {
+		pr.Skipped = "disabled in repository configuration"
+	}
+	if pr.Result.Status == StatusOutsideSchedule {
+		pr.Skipped = "outside the update schedule"
+	}
+
+	return pr
+}
+
+// GitAuthEnv returns the environment variables configuring git to authenticate
+// with the API token, without storing it in the git configuration
+func gitAuthEnv(token string) []string {
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + basicAuth(token),
+	}
+}
+
+// BasicAuth returns the HTTP basic authentication credentials of the API token
+func basicAuth(token string) string {
+	return base64.StdEncoding.EncodeToString([]byte("gitlab-ci-token:" + token))
+}
@@ -0,0 +1,177 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Bisection is the result of bisecting the updated packages with the bisect command
+type Bisection struct {
+	// Culprits are the updated packages breaking the bisect command, which were held back
+	Culprits []ComposerDiffPackage
+	// Safe are the names of the remaining updated packages
+	Safe []string
+	// Failure is the failing bisect command result with the culprits updated
+	Failure CheckResult
+	// Steps is the number of partial updates tested
+	Steps int
+}
+
+// Bisect finds the updated packages breaking the bisect command by updating subsets
+// of the packages from the original lock file, and testing each. Culprits are searched
+// until the remaining (safe) packages pass, so multiple culprits are all held back.
+// The repository is left updated with the safe packages only.
+func (u *Updater) bisect(original []byte, diff ComposerDiff, failure CheckResult) (Bisection, error) {
+	b := Bisection{Failure: failure}
+
+	lookup := map[string]ComposerDiffPackage{}
+	candidates := []string{}
+	for _, p := range diff.Packages {
+		// added & removed packages are dependencies, and cannot be updated on their own
+		if p.PreVersion != "" && p.PostVersion != "" {
+			lookup[p.Name] = p
+			candidates = append(candidates, p.Name)
+		}
+	}
+	sort.Strings(candidates)
+
+	if len(candidates) == 0 {
+		return b, fmt.Errorf("no updated packages to bisect")
+	}
+
+	u.printf("\n==========\n%s failed, bisecting %d updated packages\n==========\n", u.opts.Bisect, len(candidates))
+
+	// test updates the packages (with their dependencies) from the original lock file,
+	// and runs the bisect command. Packages which cannot be updated without the
+	// other packages fail the test.
+	test := func(packages []string) (CheckResult, error) {
+		b.Steps++
+		u.printf("Bisect step %d: updating %s\n", b.Steps, strings.Join(packages, ", "))
+
+		if err := os.WriteFile(u.composerLockFile, original, 0600); err != nil {
+			return CheckResult{}, err
+		}
+
+		args := append(append([]string{"--with-dependencies"}, packages...), u.holdArgs()...)
+		if out, err := u.composerUpdate(args...); err != nil {
+			if u.ctx.Err() != nil {
+				return CheckResult{}, err
+			}
+
+			return CheckResult{
+				Name:    u.opts.Bisect,
+				Command: u.opts.Bisect,
+				Output:  fmt.Sprintf("Updating %s failed:\n%s", strings.Join(packages, ", "), out),
+			}, nil
+		}
+
+		return u.commandCheck(u.opts.Bisect), nil
+	}
+
+	safe := candidates
+	// the bisect command already failed with all the packages updated
+	failing := true
+
+	for len(safe) > 0 {
+		if !failing {
+			r, err := test(safe)
+			if err != nil {
+				return b, err
+			}
+
+			if r.Passed {
+				break
+			}
+		}
+		failing = false
+
+		culprits := safe
+		for len(culprits) > 1 {
+			half := len(culprits) / 2
+			narrowed := false
+
+			for _, subset := range [][]string{culprits[:half], culprits[half:]} {
+				r, err := test(subset)
+				if err != nil {
+					return b, err
+				}
+
+				if !r.Passed {
+					culprits, b.Failure, narrowed = subset, r, true
+					break
+				}
+			}
+
+			if !narrowed {
+				// the failure needs packages of both halves, so all are held back
+				break
+			}
+		}
+
+		for _, name := range culprits {
+			b.Culprits = append(b.Culprits, lookup[name])
+			u.printf("Holding back %s %s\n", name, lookup[name].PostVersion)
+		}
+
+		safe = slices.DeleteFunc(slices.Clone(safe), func(name string) bool {
+			return slices.Contains(culprits, name)
+		})
+	}
+
+	sort.Slice(b.Culprits, func(i, j int) bool { return b.Culprits[i].Name < b.Culprits[j].Name })
+	b.Safe = safe
+
+	if len(safe) == 0 {
+		return b, u.partialUpdate(original, nil)
+	}
+
+	// the last test updated the safe packages, and passed
+	u.printf("The remaining %d packages pass: %s\n", len(safe), strings.Join(safe, ", "))
+
+	return b, nil
+}
+
+// BisectReport returns the markdown report of the held back packages for the
+// merge request description
+func bisectReport(b *Bisection) string {
+	if b == nil || len(b.Culprits) == 0 {
+		return ""
+	}
+
+	report := fmt.Sprintf("\n\n### Held back packages\n\n`%s` failed with all the updates. ", b.Failure.Command)
+	report += fmt.Sprintf("Bisecting the updated packages (%d steps) found the following to break it, ", b.Steps)
+	report += "so they are not included in this merge request:\n\n"
+	report += packageList(b.Culprits)
+
+	return strings.TrimSuffix(report, "\n")
+}
+
+// ReportBisection opens (or updates the existing) issue with the held back packages
+func (u *Updater) reportBisection(b Bisection) (*gitlab.Issue, error) {
+	names := []string{}
+	for _, p := range b.Culprits {
+		names = append(names, p.Name)
+	}
+
+	title := fmt.Sprintf("Composer update: %s held back on %s", strings.Join(names, ", "), u.opts.GitBranch)
+
+	var d strings.Builder
+
+	fmt.Fprintf(&d, "Updating the following packages of `%s` breaks `%s`, ", u.opts.GitBranch, b.Failure.Command)
+	d.WriteString("so they were held back from the update merge request:\n\n")
+	d.WriteString(packageList(b.Culprits))
+
+	if u.opts.JobURL != "" {
+		fmt.Fprintf(&d, "\nFailing job: %s\n", u.opts.JobURL)
+	}
+
+	d.WriteString(checksReport("Output", []CheckResult{b.Failure}))
+	d.WriteString("\n\nThis issue is updated on every run, and closed automatically once all the updates pass.")
+
+	return u.saveIssue(issueKindBisect, title, u.issueDescription(issueKindBisect, d.String()))
+}
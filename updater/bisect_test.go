@@ -0,0 +1,241 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// bisectLock returns a composer.lock with vendor/a, vendor/b & vendor/c at the given versions
+func bisectLock(a, b, c string) string {
+	pkg := func(name, version string) string {
+		return fmt.Sprintf(`{"name": "vendor/%s", "version": "%s", "source": {"type": "git", "url": "https://github.com/vendor/%s.git", "reference": "%s"}}`, name, version, name, version)
+	}
+
+	return fmt.Sprintf(`{
+    "content-hash": "abc",
+    "packages": [
+        %s,
+        %s,
+        %s
+    ],
+    "packages-dev": []
+}
+`, pkg("a", a), pkg("b", b), pkg("c", c))
+}
+
+// bisectTest are the arguments running the bisect command
+var bisectTest = []string{"-c", "vendor/bin/phpunit"}
+
+var errBisect = fmt.Errorf("exit status 1")
+
+// bisectUpdate returns the composer update arguments
+func bisectUpdate(args ...string) []string {
+	return append([]string{"update", "--no-interaction", "--no-progress"}, args...)
+}
+
+func TestUpdateBisect(t *testing.T) {
+	pre := bisectLock("1.0.0", "1.0.0", "1.0.0")
+	full := bisectLock("1.1.0", "1.1.0", "2.0.0")
+	safe := bisectLock("1.1.0", "1.1.0", "1.0.0")
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: bisectUpdate(), Do: writeLock(full)},
+		{Bin: "sh", Args: bisectTest, Output: "FAILURES!", Err: errBisect},
+		{Bin: "composer-2", Args: bisectUpdate("--with-dependencies", "vendor/a"), Do: writeLock(bisectLock("1.1.0", "1.0.0", "1.0.0"))},
+		{Bin: "sh", Args: bisectTest, Output: "OK"},
+		{Bin: "composer-2", Args: bisectUpdate("--with-dependencies", "vendor/b", "vendor/c"), Do: writeLock(bisectLock("1.0.0", "1.1.0", "2.0.0"))},
+		{Bin: "sh", Args: bisectTest, Output: "FAILURES!", Err: errBisect},
+		{Bin: "composer-2", Args: bisectUpdate("--with-dependencies", "vendor/b"), Do: writeLock(bisectLock("1.0.0", "1.1.0", "1.0.0"))},
+		{Bin: "sh", Args: bisectTest, Output: "OK"},
+		{Bin: "composer-2", Args: bisectUpdate("--with-dependencies", "vendor/c"), Do: writeLock(bisectLock("1.0.0", "1.0.0", "2.0.0"))},
+		{Bin: "sh", Args: bisectTest, Output: "Failed asserting that c works", Err: errBisect},
+		{Bin: "composer-2", Args: bisectUpdate("--with-dependencies", "vendor/a", "vendor/b"), Do: writeLock(safe)},
+		{Bin: "sh", Args: bisectTest, Output: "OK"},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/a: 1.0.0...1.1.0", "vendor/b: 1.0.0...1.1.0"})...)
+
+	u, server := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.Bisect = "vendor/bin/phpunit"
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusCreated || result.Checksum != checksum(safe) {
+		t.Errorf("expected a merge request with the safe updates, got %v %s", result.Status, result.Checksum)
+	}
+
+	b := result.Bisection
+	if b == nil || len(b.Culprits) != 1 || b.Culprits[0].Name != "vendor/c" || b.Steps != 5 {
+		t.Fatalf("expected vendor/c to be held back, got %+v", b)
+	}
+
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 1 || !strings.Contains(mrs[0].Description, "### Held back packages") || !strings.Contains(mrs[0].Description, "[vendor/c]") {
+		t.Fatalf("expected the merge request to list the held back package, got %v", mrs)
+	}
+
+	if meta, ok := u.parseMRMetadata(mrs[0]); !ok || meta.Checksum != checksum(safe) || meta.FullChecksum != checksum(full) {
+		t.Errorf("expected the metadata to contain the full update checksum, got %+v", meta)
+	}
+
+	issues := server.OpenIssues()
+	if len(issues) != 1 || !strings.Contains(issues[0].Title, "vendor/c held back") || !strings.Contains(issues[0].Description, "Failed asserting that c works") {
+		t.Fatalf("expected an issue reporting vendor/c, got %v", issues)
+	}
+}
+
+func TestUpdateBisectedMRExists(t *testing.T) {
+	pre := bisectLock("1.0.0", "1.0.0", "1.0.0")
+	full := bisectLock("1.1.0", "1.1.0", "2.0.0")
+	safe := bisectLock("1.1.0", "1.1.0", "1.0.0")
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: bisectUpdate(), Do: writeLock(full)},
+	}}
+
+	u, server := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.Bisect = "vendor/bin/phpunit"
+
+	// the open merge request was bisected from the same full update
+	server.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Composer update: 2 packages", SourceBranch: "composer-update/main", TargetBranch: "main",
+		Description: "## Updated Composer Packages" + mrMarker(MRMetadata{Checksum: checksum(safe), TargetBranch: "main", FullChecksum: checksum(full)}),
+	})
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusExists || result.Bisection != nil || hasCall(runner.Calls, "sh") {
+		t.Errorf("expected the bisected merge request to exist without bisecting, got %v %+v", result.Status, result.Bisection)
+	}
+}
+
+func TestBisect(t *testing.T) {
+	pre := bisectLock("1.0.0", "1.0.0", "1.0.0")
+	diff := ComposerDiff{Packages: []ComposerDiffPackage{
+		{Name: "vendor/a", PreVersion: "1.0.0", PostVersion: "2.0.0"},
+		{Name: "vendor/b", PreVersion: "1.0.0", PostVersion: "1.1.0"},
+		{Name: "vendor/c", PreVersion: "1.0.0", PostVersion: "2.0.0"},
+	}}
+	update := func(packages ...string) []string {
+		return bisectUpdate(append([]string{"--with-dependencies"}, packages...)...)
+	}
+	pass := func(packages ...string) []FakeResponse {
+		return []FakeResponse{{Bin: "composer-2", Args: update(packages...)}, {Bin: "sh", Args: bisectTest, Output: "OK"}}
+	}
+	fail := func(packages ...string) []FakeResponse {
+		return []FakeResponse{{Bin: "composer-2", Args: update(packages...)}, {Bin: "sh", Args: bisectTest, Output: "FAILURES!", Err: errBisect}}
+	}
+
+	tests := []struct {
+		name      string
+		responses [][]FakeResponse
+		culprits  []string
+		safe      []string
+		steps     int
+		failure   string
+	}{
+		{
+			name:      "failing first half",
+			responses: [][]FakeResponse{fail("vendor/a"), pass("vendor/b", "vendor/c")},
+			culprits:  []string{"vendor/a"},
+			safe:      []string{"vendor/b", "vendor/c"},
+			steps:     2,
+			failure:   "FAILURES!",
+		},
+		{
+			name: "two culprits",
+			responses: [][]FakeResponse{
+				fail("vendor/a"),
+				fail("vendor/b", "vendor/c"), pass("vendor/b"), fail("vendor/c"),
+				pass("vendor/b"),
+			},
+			culprits: []string{"vendor/a", "vendor/c"},
+			safe:     []string{"vendor/b"},
+			steps:    5,
+			failure:  "FAILURES!",
+		},
+		{
+			name: "half cannot resolve",
+			responses: [][]FakeResponse{
+				{{Bin: "composer-2", Args: update("vendor/a"), Output: "Your requirements could not be resolved", Err: fmt.Errorf("exit status 2")}},
+				pass("vendor/b", "vendor/c"),
+			},
+			culprits: []string{"vendor/a"},
+			safe:     []string{"vendor/b", "vendor/c"},
+			steps:    2,
+			failure:  "Your requirements could not be resolved",
+		},
+		{
+			name: "all culprits",
+			responses: [][]FakeResponse{
+				fail("vendor/a"),
+				fail("vendor/b", "vendor/c"), fail("vendor/b"),
+				fail("vendor/c"),
+			},
+			culprits: []string{"vendor/a", "vendor/b", "vendor/c"},
+			steps:    4,
+			failure:  "FAILURES!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &FakeRunner{}
+			for _, r := range tt.responses {
+				runner.Responses = append(runner.Responses, r...)
+			}
+
+			u, _ := setup(t, runner, "main")
+			u.opts.Bisect = "vendor/bin/phpunit"
+			if err := u.init(); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := u.bisect([]byte(pre), diff, CheckResult{Output: "FAILURES!"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(runner.Unused()) > 0 {
+				t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+			}
+
+			culprits := []string{}
+			for _, p := range b.Culprits {
+				culprits = append(culprits, p.Name)
+			}
+
+			if strings.Join(culprits, ",") != strings.Join(tt.culprits, ",") || strings.Join(b.Safe, ",") != strings.Join(tt.safe, ",") {
+				t.Errorf("expected culprits %v & safe %v, got %v & %v", tt.culprits, tt.safe, culprits, b.Safe)
+			}
+
+			if b.Steps != tt.steps || !strings.Contains(b.Failure.Output, tt.failure) {
+				t.Errorf("expected %d steps failing with %q, got %d %q", tt.steps, tt.failure, b.Steps, b.Failure.Output)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package updater
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBranchName is the default merge request branch name template
+	DefaultBranchName = "composer-update/{target}"
+	// DefaultPackageBranchName is the default branch name template in package mode
+	DefaultPackageBranchName = "composer-update/{package}"
+	// DefaultTargetPackageBranchName is the default branch name template in package
+	// mode with multiple target branches
+	DefaultTargetPackageBranchName = "composer-update/{target}/{package}"
+)
+
+var (
+	// branchVarRe matches the variables of a branch name template, eg: {target}
+	branchVarRe = regexp.MustCompile(`\{([a-z]+)\}`)
+
+	// invalidBranchRe matches characters & sequences not allowed in git branch names
+	invalidBranchRe = regexp.MustCompile(`[\s~^:?*\[\\]+|\.\.+|@\{|//+`)
+
+	// branchVars are the supported branch name template variables
+	branchVars = []string{"target", "group", "checksum", "date", "package"}
+)
+
+// BranchTemplate returns the configured branch name template, or the default of the merge request mode
+func (o *Options) branchTemplate() string {
+	if o.BranchName != "" {
+		return o.BranchName
+	}
+
+	if o.MRMode == "package" && len(o.Branches) > 1 {
+		return DefaultTargetPackageBranchName
+	}
+
+	if o.MRMode == "package" {
+		return DefaultPackageBranchName
+	}
+
+	return DefaultBranchName
+}
+
+// ValidateBranchTemplate returns an error for unknown template variables, or a
+// template resulting in the same branch for all packages in package mode, or
+// for all target branches
+func (o *Options) validateBranchTemplate() error {
+	template := o.branchTemplate()
+
+	for _, match := range branchVarRe.FindAllStringSubmatch(template, -1) {
+		known := false
+		for _, v := range branchVars {
+			if match[1] == v {
+				known = true
+			}
+		}
+
+		if !known {
+			return fmt.Errorf("invalid branch name \"%s\": unknown variable {%s}", template, match[1])
+		}
+	}
+
+	if o.MRMode == "package" && !strings.Contains(template, "{package}") {
+		return fmt.Errorf("invalid branch name \"%s\": {package} is required in package mode", template)
+	}
+
+	if len(o.Branches) > 1 && !strings.Contains(template, "{target}") {
+		return fmt.Errorf("invalid branch name \"%s\": {target} is required with multiple target branches", template)
+	}
+
+	return nil
+}
+
+// BranchName returns the merge request branch name from the template, for the
+// checksum & package (in package mode) of the update
+func (u *Updater) branchName(checksum, pkg string) string {
+	group := u.mrGroup
+	if group == "" {
+		group = "all"
+	}
+
+	if len(checksum) > 8 {
+		checksum = checksum[:8]
+	}
+
+	vars := map[string]string{
+		"target":   u.opts.GitBranch,
+		"group":    group,
+		"checksum": checksum,
+		"date":     time.Now().UTC().Format("20060102"),
+		"package":  strings.ReplaceAll(pkg, "/", "-"),
+	}
+
+	name := branchVarRe.ReplaceAllStringFunc(u.opts.branchTemplate(), func(m string) string {
+		return vars[m[1:len(m)-1]]
+	})
+
+	name = invalidBranchRe.ReplaceAllStringFunc(strings.ToLower(name), func(m string) string {
+		if strings.HasPrefix(m, "/") {
+			return "/"
+		}
+		return "-"
+	})
+
+	return u.opts.BranchPrefix + strings.Trim(name, "/.-")
+}
@@ -0,0 +1,115 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestBranchName(t *testing.T) {
+	date := time.Now().UTC().Format("20060102")
+
+	tests := []struct {
+		template, prefix, group, pkg string
+		want                         string
+	}{
+		{"", "", "", "", "composer-update/release/2.x"},
+		{"", "feature/", "", "", "feature/composer-update/release/2.x"},
+		{"deps/{target}-{group}-{checksum}", "", "framework", "", "deps/release/2.x-framework-0123abcd"},
+		{"deps/{group}/{date}", "", "", "", "deps/all/" + date},
+		{"deps/{package}", "", "", "Vendor/Pkg", "deps/vendor-pkg"},
+		{"deps: {target}..x", "", "", "", "deps-release/2.x-x"},
+	}
+
+	for _, test := range tests {
+		u := New(Options{GitBranch: "release/2.x", BranchName: test.template, BranchPrefix: test.prefix})
+		u.mrGroup = test.group
+
+		if got := u.branchName("0123abcdef", test.pkg); got != test.want {
+			t.Errorf("%q: expected %s, got %s", test.template, test.want, got)
+		}
+	}
+
+	branches := []TargetBranch{{Name: "main"}, {Name: "release/2.x"}}
+
+	u := New(Options{GitBranch: "release/2.x", MRMode: "package", Branches: branches})
+	if got := u.branchName("0123abcdef", "vendor/pkg"); got != "composer-update/release/2.x/vendor-pkg" {
+		t.Errorf("expected the package branch of the target, got %s", got)
+	}
+
+	for _, o := range []Options{
+		{BranchName: "deps/{unknown}"},
+		{MRMode: "package", BranchName: "deps/{target}"},
+		{MRMode: "package", BranchName: "deps/{package}", Branches: branches},
+		{BranchName: "deps/{group}", Branches: branches},
+	} {
+		if err := o.validateBranchTemplate(); err == nil {
+			t.Errorf("expected %q to be invalid in %s mode with %d branches", o.BranchName, o.MRMode, len(o.Branches))
+		}
+	}
+
+	for _, o := range []Options{{MRMode: "package", Branches: branches}, {Branches: branches}} {
+		if err := o.validateBranchTemplate(); err != nil {
+			t.Errorf("expected the default template to be valid in %s mode: %v", o.MRMode, err)
+		}
+	}
+}
+
+func TestUpdateReusesStableBranch(t *testing.T) {
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/pkg: 1.0.0...1.1.0"})...)
+
+	u, server := setup(t, runner, "main")
+	u.opts.ReplaceOpen = true
+
+	stable := server.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Composer update: 1 package", SourceBranch: "composer-update/main", TargetBranch: "main",
+		Description: botMRDescription("old"),
+	})
+	legacy := server.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Composer update: 1 package", SourceBranch: "composer-update-20240101120000", TargetBranch: "main",
+		Description: botMRDescription("older"),
+	})
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusRegenerated || result.MergeRequest == nil || result.MergeRequest.IID != stable.IID {
+		t.Fatalf("expected the merge request of the stable branch to be updated, got %v %v", result.Status, result.MergeRequest)
+	}
+
+	if !hasCall(runner.Calls, "git", "push", "--force", "origin", "composer-update/main") {
+		t.Error("expected the stable branch to be force pushed")
+	}
+
+	if meta, _ := u.parseMRMetadata(server.MergeRequest(stable.IID)); meta.Checksum != checksum(postLock) {
+		t.Errorf("expected the checksum to be updated, got %s", meta.Checksum)
+	}
+
+	if d := server.MergeRequest(stable.IID).Description; !strings.Contains(d, fmt.Sprintf("This merge request replaces !%d", legacy.IID)) {
+		t.Errorf("expected the regenerated description to list the superseded merge request:\n%s", d)
+	}
+
+	if len(result.Superseded) != 1 || server.MergeRequest(legacy.IID).State != "closed" {
+		t.Error("expected the legacy merge request to be closed")
+	}
+
+	if len(server.MergeRequests) != 2 {
+		t.Errorf("expected no new merge request, got %d", len(server.MergeRequests))
+	}
+}
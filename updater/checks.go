@@ -0,0 +1,192 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ValidationChecks are all the available post-update validation checks
+var ValidationChecks = []string{"validate", "content-hash", "install", "platform-reqs"}
+
+// maxCheckOutputLines is the number of output lines of a check included in the merge request
+const maxCheckOutputLines = 30
+
+// CheckResult is the result of a single post-update check
+type CheckResult struct {
+	// Name is the name of the check, eg: "validate"
+	Name string
+	// Command is the executed command
+	Command string
+	// Passed is whether the check passed
+	Passed bool
+	// Duration is how long the check took
+	Duration time.Duration
+	// Output is the check output
+	Output string
+}
+
+// RunValidation runs the configured post-update validation checks
+func (u *Updater) runValidation(lock ComposerLock) []CheckResult {
+	results := []CheckResult{}
+
+	for _, check := range u.opts.Validate {
+		var r CheckResult
+
+		switch check {
+		case "validate":
+			r = u.composerCheck(check, "validate", "--strict", "--no-interaction")
+		case "content-hash":
+			r = u.contentHashCheck(lock)
+		case "install":
+			r = u.composerCheck(check, "install", "--dry-run", "--no-interaction", "--no-progress")
+		case "platform-reqs":
+			r = u.composerCheck(check, "check-platform-reqs", "--no-interaction")
+		}
+
+		status := "passed"
+		if !r.Passed {
+			status = "failed"
+		}
+		u.printf("Validation %s: %s\n", r.Name, status)
+
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// RunVerification runs the configured verify commands
+func (u *Updater) runVerification() []CheckResult {
+	results := []CheckResult{}
+
+	for _, command := range u.opts.Verify {
+		u.printf("Verifying: %s\n", command)
+
+		r := u.commandCheck(command)
+		if !r.Passed {
+			u.printf("Verification failed: %s\n", command)
+		}
+
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// CommandCheck runs a shell command as a check
+func (u *Updater) commandCheck(command string) CheckResult {
+	start := time.Now()
+
+	c := Command{Dir: u.opts.RepoDir, Bin: "sh", Args: []string{"-c", command}, Output: u.opts.Output}
+	if u.opts.untrustedCommands {
+		// the commands are set by the repository, and must not access the central token
+		c.Secrets = u.opts.Secrets()
+	}
+
+	out, err := u.opts.Runner.Run(u.ctx, c)
+
+	return CheckResult{
+		Name:     command,
+		Command:  command,
+		Passed:   err == nil,
+		Duration: time.Since(start),
+		Output:   out,
+	}
+}
+
+// ComposerCheck runs a composer command as a check
+func (u *Updater) composerCheck(name string, args ...string) CheckResult {
+	start := time.Now()
+
+	out, err := u.composer(args...)
+
+	return CheckResult{
+		Name:     name,
+		Command:  "composer " + strings.Join(args, " "),
+		Passed:   err == nil,
+		Duration: time.Since(start),
+		Output:   out,
+	}
+}
+
+// ContentHashCheck checks the lock file content-hash matches composer.json
+func (u *Updater) contentHashCheck(lock ComposerLock) CheckResult {
+	r := CheckResult{Name: "content-hash", Command: "composer.lock content-hash"}
+	start := time.Now()
+
+	b, err := os.ReadFile(filepath.Join(u.opts.RepoDir, "composer.json"))
+	if err != nil {
+		r.Output = err.Error()
+		return r
+	}
+
+	hash, err := composerContentHash(b)
+	if err != nil {
+		r.Output = fmt.Sprintf("error parsing composer.json: %s", err)
+		return r
+	}
+
+	r.Duration = time.Since(start)
+	r.Passed = hash == lock.ContentHash
+
+	if r.Passed {
+		r.Output = "content-hash " + hash + " matches composer.json"
+	} else {
+		r.Output = fmt.Sprintf("content-hash %s does not match composer.json (%s)", lock.ContentHash, hash)
+	}
+
+	return r
+}
+
+// ChecksReport returns the markdown report of checks for the merge request description
+func checksReport(title string, results []CheckResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+
+	report := "\n\n### " + title + "\n\n"
+	report += "| Check | Result | Duration |\n|---|---|---|\n"
+
+	for _, r := range results {
+		status := "✅ passed"
+		if !r.Passed {
+			status = "❌ failed"
+		}
+		report += fmt.Sprintf("| `%s` | %s | %s |\n", strings.ReplaceAll(r.Command, "|", "\\|"), status, r.Duration.Round(time.Millisecond))
+	}
+
+	for _, r := range results {
+		if r.Passed || strings.TrimSpace(r.Output) == "" {
+			continue
+		}
+
+		report += fmt.Sprintf("\n<details><summary><code>%s</code> output</summary>\n\n```\n%s\n```\n\n</details>\n", r.Command, truncateLines(r.Output, maxCheckOutputLines))
+	}
+
+	return strings.TrimSuffix(report, "\n")
+}
+
+// FailedChecks returns the names of all failed checks
+func failedChecks(results []CheckResult) []string {
+	failed := []string{}
+	for _, r := range results {
+		if !r.Passed {
+			failed = append(failed, r.Name)
+		}
+	}
+
+	return failed
+}
+
+// TruncateLines returns the last n lines of the output
+func truncateLines(output string, n int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > n {
+		lines = append([]string{fmt.Sprintf("... %d lines truncated", len(lines)-n)}, lines[len(lines)-n:]...)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,139 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComposerContentHash(t *testing.T) {
+	composerJSON := `{
+    "name": "acme/app",
+    "description": "Ignored",
+    "require": {"php": "^8.1", "vendor/pkg": "^1.0"},
+    "require-dev": {},
+    "extra": {"symfony": {"allow-contrib": false, "require": "6.4.*"}, "note": "café <b>&</b>"},
+    "config": {"sort-packages": true, "platform": {"php": "8.1.2"}},
+    "minimum-stability": "stable"
+}`
+
+	hash, err := composerContentHash([]byte(composerJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash != "c0c1cc81c94cfc814b6e92e35a5c536c" {
+		t.Errorf("unexpected content-hash %s", hash)
+	}
+}
+
+func TestUpdateValidation(t *testing.T) {
+	for _, policy := range []string{"abort", "report"} {
+		runner := &FakeRunner{Responses: []FakeResponse{
+			{Bin: "git", Args: []string{"checkout", "main"}},
+			{Bin: "git", Args: []string{"pull", "--rebase"}},
+			{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+			{Bin: "composer-2", Args: []string{"validate", "--strict", "--no-interaction"}, Output: "./composer.json is valid"},
+			{Bin: "composer-2", Args: []string{"install", "--dry-run", "--no-interaction", "--no-progress"}, Output: "Nothing to install, update or remove"},
+			{Bin: "composer-2", Args: []string{"check-platform-reqs", "--no-interaction"}, Output: "ext-intl n/a missing", Err: fmt.Errorf("exit status 2")},
+		}}
+		if policy == "report" {
+			runner.Responses = append(runner.Responses, gitSetupResponses()...)
+			runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/pkg: 1.0.0...1.1.0"})...)
+		}
+
+		u, server := setup(t, runner, "main")
+		u.opts.Validate = []string{"all"}
+		u.opts.ValidatePolicy = policy
+
+		// the content-hash of the test lock files does not match
+		if err := os.WriteFile(filepath.Join(u.opts.RepoDir, "composer.json"), []byte(`{"require": {"vendor/pkg": "^1.0"}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := u.Run(context.Background())
+
+		if len(result.Validation) != 4 || len(failedChecks(result.Validation)) != 2 {
+			t.Errorf("%s: unexpected validation results: %+v", policy, result.Validation)
+		}
+
+		if policy == "abort" {
+			if err == nil || err.Error() != "validation failed: content-hash, platform-reqs" {
+				t.Errorf("expected validation to abort, got %v", err)
+			}
+			if len(server.MergeRequests) != 0 {
+				t.Error("expected no merge request to be created")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mrs := server.OpenMergeRequests()
+		if len(mrs) != 1 {
+			t.Fatalf("expected a merge request, got %d", len(mrs))
+		}
+
+		for _, expected := range []string{"### Validation", "| `composer validate --strict --no-interaction` | ✅ passed |", "ext-intl n/a missing"} {
+			if !strings.Contains(mrs[0].Description, expected) {
+				t.Errorf("expected description to contain %q:\n%s", expected, mrs[0].Description)
+			}
+		}
+	}
+}
+
+func TestUpdateVerification(t *testing.T) {
+	for _, policy := range []string{"draft", "skip"} {
+		runner := &FakeRunner{Responses: []FakeResponse{
+			{Bin: "git", Args: []string{"checkout", "main"}},
+			{Bin: "git", Args: []string{"pull", "--rebase"}},
+			{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+			{Bin: "sh", Args: []string{"-c", "php artisan about"}, Output: "Laravel 11"},
+			{Bin: "sh", Args: []string{"-c", "vendor/bin/phpunit"}, Output: "FAILURES!\nTests: 10, Assertions: 20, Failures: 1.", Err: fmt.Errorf("exit status 1")},
+		}}
+		if policy == "draft" {
+			runner.Responses = append(runner.Responses, gitSetupResponses()...)
+			runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/pkg: 1.0.0...1.1.0"})...)
+		}
+
+		u, server := setup(t, runner, "main")
+		u.opts.Verify = []string{"php artisan about", "vendor/bin/phpunit"}
+		u.opts.VerifyPolicy = policy
+
+		result, err := u.Run(context.Background())
+
+		if len(result.Verification) != 2 || !result.Verification[0].Passed || result.Verification[1].Passed {
+			t.Errorf("%s: unexpected verification results: %+v", policy, result.Verification)
+		}
+
+		if policy == "skip" {
+			if err == nil || !strings.Contains(err.Error(), "merge request skipped: vendor/bin/phpunit") {
+				t.Errorf("expected merge request to be skipped, got %v", err)
+			}
+			if hasCall(runner.Calls, "git", "push") || len(server.MergeRequests) != 0 {
+				t.Error("expected nothing to be pushed")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mrs := server.OpenMergeRequests()
+		if len(mrs) != 1 || !mrs[0].Draft || !strings.HasPrefix(mrs[0].Title, "Draft: ") {
+			t.Fatalf("expected a draft merge request, got %v", mrs)
+		}
+
+		for _, expected := range []string{"### Verification", "| `vendor/bin/phpunit` | ❌ failed |", "Failures: 1."} {
+			if !strings.Contains(mrs[0].Description, expected) {
+				t.Errorf("expected description to contain %q:\n%s", expected, mrs[0].Description)
+			}
+		}
+	}
+}
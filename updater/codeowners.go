@@ -0,0 +1,199 @@
+package updater
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// CODEOWNERS locations in the order GitLab looks for them
+	codeOwnersFiles = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+	// files modified by a composer update which CODEOWNERS rules are matched against
+	codeOwnersPaths = []string{"composer.json", "composer.lock", "vendor/composer/installed.json"}
+)
+
+// CodeOwnersRule is a single CODEOWNERS pattern & its owners
+type codeOwnersRule struct {
+	Pattern *regexp.Regexp
+	Owners  []string
+}
+
+// CodeOwnersSection is a (optionally named) section of CODEOWNERS rules
+type codeOwnersSection struct {
+	Name          string
+	DefaultOwners []string
+	Rules         []codeOwnersRule
+}
+
+// GetCodeOwners returns the users & groups owning the composer files
+// according to the repository's CODEOWNERS file (if it exists)
+func (u *Updater) getCodeOwners() []string {
+	owners := []string{}
+
+	for _, f := range codeOwnersFiles {
+		file := path.Join(u.opts.RepoDir, f)
+		if !isFile(file) {
+			continue
+		}
+
+		sections, err := parseCodeOwners(file)
+		if err != nil {
+			u.printf("Error parsing %s: %s\n", f, err)
+			return owners
+		}
+
+		for _, p := range codeOwnersPaths {
+			owners = append(owners, matchCodeOwners(sections, p)...)
+		}
+
+		// GitLab only uses the first CODEOWNERS file found
+		return owners
+	}
+
+	return owners
+}
+
+// ParseCodeOwners parses a CODEOWNERS file into sections
+func parseCodeOwners(file string) ([]codeOwnersSection, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sectionRe := regexp.MustCompile(`^\^?\[([^\]]+)\](\[\d+\])?(.*)$`)
+
+	sections := []codeOwnersSection{{}}
+	current := &sections[0]
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if match := sectionRe.FindStringSubmatch(line); match != nil {
+			sections = append(sections, codeOwnersSection{
+				Name:          match[1],
+				DefaultOwners: codeOwnersList(strings.Fields(match[3])),
+			})
+			current = &sections[len(sections)-1]
+			continue
+		}
+
+		pattern, owners := splitCodeOwnersLine(line)
+		if pattern == "" {
+			continue
+		}
+
+		if len(owners) == 0 {
+			owners = current.DefaultOwners
+		}
+
+		current.Rules = append(current.Rules, codeOwnersRule{
+			Pattern: codeOwnersPattern(pattern),
+			Owners:  owners,
+		})
+	}
+
+	return sections, scanner.Err()
+}
+
+// SplitCodeOwnersLine splits a CODEOWNERS line into the pattern & owners,
+// taking escaped spaces in the pattern into account
+func splitCodeOwnersLine(line string) (string, []string) {
+	pattern := ""
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) && line[i+1] == ' ' {
+			pattern += " "
+			i++
+			continue
+		}
+		if line[i] == ' ' || line[i] == '\t' {
+			break
+		}
+		pattern += string(line[i])
+	}
+
+	return pattern, codeOwnersList(strings.Fields(line[i:]))
+}
+
+// CodeOwnersList filters the owner list, ignoring comments and role references (@@developer)
+func codeOwnersList(fields []string) []string {
+	owners := []string{}
+	for _, o := range fields {
+		if strings.HasPrefix(o, "#") {
+			break
+		}
+		if strings.HasPrefix(o, "@@") {
+			continue
+		}
+		owners = append(owners, o)
+	}
+
+	return owners
+}
+
+// CodeOwnersPattern converts a CODEOWNERS path pattern into a regular expression
+func codeOwnersPattern(pattern string) *regexp.Regexp {
+	// a trailing slash matches everything within the directory
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	// relative paths match at any depth
+	if strings.HasPrefix(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else if pattern != "*" {
+		pattern = "**/" + pattern
+	}
+
+	re := "^"
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re += "(.*/)?"
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re += ".*"
+			i++
+		case c == '*':
+			if pattern == "*" {
+				re += ".*"
+			} else {
+				re += "[^/]*"
+			}
+		case c == '?':
+			re += "[^/]"
+		default:
+			re += regexp.QuoteMeta(string(c))
+		}
+	}
+
+	// a matching directory matches all of its contents
+	re += "(/.*)?$"
+
+	return regexp.MustCompile(re)
+}
+
+// MatchCodeOwners returns the owners of a path. Within each section the last
+// matching rule applies, and owners from all sections are combined.
+func matchCodeOwners(sections []codeOwnersSection, file string) []string {
+	owners := []string{}
+
+	for _, s := range sections {
+		for i := len(s.Rules) - 1; i >= 0; i-- {
+			if s.Rules[i].Pattern.MatchString(file) {
+				owners = append(owners, s.Rules[i].Owners...)
+				break
+			}
+		}
+	}
+
+	return owners
+}
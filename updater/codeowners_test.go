@@ -0,0 +1,159 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCodeOwnersPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		matches bool
+	}{
+		// wildcard
+		{"*", "composer.lock", true},
+		{"*", "vendor/composer/installed.json", true},
+		// relative paths match at any depth
+		{"composer.lock", "composer.lock", true},
+		{"composer.lock", "app/composer.lock", true},
+		{"*.lock", "app/composer.lock", true},
+		{"*.json", "composer.lock", false},
+		// anchored paths only match from the repository root
+		{"/composer.lock", "composer.lock", true},
+		{"/composer.lock", "app/composer.lock", false},
+		{"/*.json", "vendor/composer/installed.json", false},
+		// directories match all their contents
+		{"vendor/", "vendor/composer/installed.json", true},
+		{"/vendor/composer", "vendor/composer/installed.json", true},
+		{"/vendor/", "app/vendor/composer/installed.json", false},
+		{"composer/", "vendor/composer/installed.json", true},
+		// globstar & single character wildcards
+		{"/vendor/**/installed.json", "vendor/composer/installed.json", true},
+		{"/**/installed.json", "vendor/composer/installed.json", true},
+		{"composer.?son", "composer.json", true},
+		{"composer.?son", "composer.lock", false},
+		// wildcards do not match directory separators
+		{"/vendor/*.json", "vendor/composer/installed.json", false},
+		// special characters are escaped
+		{"composer+lock", "composerxlock", false},
+	}
+
+	for _, test := range tests {
+		if m := codeOwnersPattern(test.pattern).MatchString(test.file); m != test.matches {
+			t.Errorf("%s %s: expected %v, got %v", test.pattern, test.file, test.matches, m)
+		}
+	}
+}
+
+func TestSplitCodeOwnersLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		pattern string
+		owners  []string
+	}{
+		{"composer.lock @alice @php/team", "composer.lock", []string{"@alice", "@php/team"}},
+		{"composer.lock\t@alice", "composer.lock", []string{"@alice"}},
+		{`/my\ dir/ @alice bob@example.com`, "/my dir/", []string{"@alice", "bob@example.com"}},
+		{"composer.lock @alice # the lock file @bob", "composer.lock", []string{"@alice"}},
+		{"composer.lock @@developer @alice", "composer.lock", []string{"@alice"}},
+		{"composer.lock", "composer.lock", []string{}},
+	}
+
+	for _, test := range tests {
+		pattern, owners := splitCodeOwnersLine(test.line)
+		if pattern != test.pattern || !reflect.DeepEqual(owners, test.owners) {
+			t.Errorf("%q: expected %q %v, got %q %v", test.line, test.pattern, test.owners, pattern, owners)
+		}
+	}
+}
+
+func TestMatchCodeOwners(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "CODEOWNERS")
+	content := `# global rules
+*             @alice
+/composer.*   @bob
+composer.lock @carol
+
+^[Backend][2] @backend
+/vendor/
+composer.json @dave
+
+[Docs]
+/docs/ @erin
+`
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sections, err := parseCodeOwners(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sections) != 3 || sections[1].Name != "Backend" || !reflect.DeepEqual(sections[1].DefaultOwners, []string{"@backend"}) {
+		t.Fatalf("unexpected sections: %+v", sections)
+	}
+
+	tests := []struct {
+		file   string
+		owners []string
+	}{
+		// the last matching rule of a section wins
+		{"composer.lock", []string{"@carol"}},
+		{"composer.json", []string{"@bob", "@dave"}},
+		{"README.md", []string{"@alice"}},
+		// rules without owners use the section default owners
+		{"vendor/composer/installed.json", []string{"@alice", "@backend"}},
+		// owners of all sections are combined
+		{"docs/index.md", []string{"@alice", "@erin"}},
+	}
+
+	for _, test := range tests {
+		if owners := matchCodeOwners(sections, test.file); !reflect.DeepEqual(owners, test.owners) {
+			t.Errorf("%s: expected %v, got %v", test.file, test.owners, owners)
+		}
+	}
+}
+
+func TestCodeOwnersReviewers(t *testing.T) {
+	u, server := setupGitLab(t)
+	u.opts.RepoDir = t.TempDir()
+	u.opts.CodeOwners = true
+	u.opts.Reviewers = []string{"alice"}
+
+	alice := server.AddUser(2, "alice", "alice@example.com", true)
+	bob := server.AddUser(3, "bob", "bob@example.com", true)
+	carol := server.AddUser(4, "carol", "carol@example.com", true)
+	server.AddUser(5, "dave", "dave@example.com", true)
+	server.AddGroup("php/team", carol.ID)
+
+	// the first CODEOWNERS file found is used
+	for file, content := range map[string]string{
+		"docs/CODEOWNERS":    "composer.json @bob\n/vendor/ @php/team\n",
+		".gitlab/CODEOWNERS": "* @dave\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(u.opts.RepoDir, filepath.Dir(file)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(u.opts.RepoDir, file), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mr, err := u.createMergeRequest("Composer update: 1 package", "", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []int{}
+	for _, r := range mr.Reviewers {
+		ids = append(ids, r.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []int{alice.ID, bob.ID, carol.ID}) {
+		t.Errorf("expected alice, bob & the php/team group to review, got %v", ids)
+	}
+}
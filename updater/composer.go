@@ -0,0 +1,369 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// networkErrors match composer output of transport & timeout failures, and of rate limited
+// or server error responses, which may succeed when retried. Other HTTP errors (eg: 404)
+// are permanent.
+var networkErrors = regexp.MustCompile(`(?i)(curl error (\d+)|could not resolve (host|proxy)|connection (timed out|refused|reset)|operation timed out|ssl connect error|failed to open stream: connection|HTTP/[0-9.]+ (429|5[0-9][0-9])|returned error: (429|5[0-9][0-9]))`)
+
+var (
+	problemRe = regexp.MustCompile(`^\s*Problem \d+\s*$`)
+
+	// packageNameRe matches composer package names, eg: vendor/package
+	packageNameRe = regexp.MustCompile(`\b[a-z0-9](?:[_.-]?[a-z0-9]+)*/[a-z0-9](?:(?:[_.]|-{1,2})?[a-z0-9]+)*\b`)
+)
+
+// IsNetworkError returns whether the composer output contains a network error
+func isNetworkError(out string) bool {
+	for _, m := range networkErrors.FindAllStringSubmatch(out, -1) {
+		// curl error 22 is an HTTP error response, only retried for server errors
+		if m[2] != "22" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ComposerUpdate will update composer (with the extra arguments, eg: package
+// names), for the target platform if set
+func (u *Updater) composerUpdate(extra ...string) (string, error) {
+	args := []string{"update", "--no-interaction", "--no-progress"}
+
+	args = append(args, u.opts.ComposerFlags...)
+	args = append(args, extra...)
+
+	if u.opts.PlatformPHP == "" {
+		return u.composer(args...)
+	}
+
+	restore, err := u.setPlatform()
+	if err != nil {
+		return "", fmt.Errorf("setting the target platform: %w", err)
+	}
+
+	out, err := u.composer(args...)
+
+	if restoreErr := restore(err == nil); restoreErr != nil && err == nil {
+		return out, restoreErr
+	}
+
+	return out, err
+}
+
+// PartialUpdate restores the original composer.lock, and updates the given packages
+// only, keeping the held back packages. Arguments may include flags, eg: --with-dependencies.
+func (u *Updater) partialUpdate(original []byte, args []string) error {
+	if err := os.WriteFile(u.composerLockFile, original, 0600); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	if _, err := u.composerUpdate(append(args, u.holdArgs()...)...); err != nil {
+		return fmt.Errorf("updating %s: %w", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
+
+// HoldArgs returns the composer update arguments constraining the held back packages
+// to their locked version, so they are not updated as a dependency of other packages
+func (u *Updater) holdArgs() []string {
+	args := []string{}
+	if u.opts.ComposerVersion == 1 {
+		// composer 1 does not support temporary constraints
+		return args
+	}
+
+	for name, version := range u.holds {
+		args = append(args, fmt.Sprintf("--with=%s:%s", name, version))
+	}
+	sort.Strings(args)
+
+	return args
+}
+
+// Composer runs a composer command with the configured PHP binary, environment
+// & timeout, retrying with a backoff delay after network errors
+func (u *Updater) composer(args ...string) (string, error) {
+	delay := u.opts.ComposerRetryDelay
+
+	for attempt := 0; ; attempt++ {
+		out, err := u.composerRun(args...)
+		if err == nil || attempt >= u.opts.ComposerRetries || !isNetworkError(out) {
+			return out, err
+		}
+
+		u.printf("\nComposer failed with a network error, retrying in %s (retry %d of %d)\n", delay, attempt+1, u.opts.ComposerRetries)
+
+		select {
+		case <-time.After(delay):
+		case <-u.ctx.Done():
+			return out, u.ctx.Err()
+		}
+
+		delay *= 2
+	}
+}
+
+// ComposerRun runs a single composer command
+func (u *Updater) composerRun(args ...string) (string, error) {
+	c := Command{Dir: u.opts.RepoDir, Bin: u.opts.ComposerPath, Args: args, Output: u.opts.Output}
+
+	if u.opts.PHPPath != "" {
+		c.Bin = u.opts.PHPPath
+		c.Args = []string{}
+		for _, ini := range u.opts.PHPIni {
+			c.Args = append(c.Args, "-d", ini)
+		}
+		c.Args = append(c.Args, u.opts.ComposerPath)
+		c.Args = append(c.Args, args...)
+	}
+
+	if u.opts.ComposerMemoryLimit != "" {
+		c.Env = append(c.Env, "COMPOSER_MEMORY_LIMIT="+u.opts.ComposerMemoryLimit)
+	}
+
+	if u.opts.untrustedCommands {
+		// composer scripts are set by the repository, and must not access the central token
+		c.Secrets = u.opts.Secrets()
+	}
+
+	ctx := u.ctx
+	if u.opts.ComposerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(u.ctx, u.opts.ComposerTimeout)
+		defer cancel()
+	}
+
+	out, err := u.opts.Runner.Run(ctx, c)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && u.ctx.Err() == nil {
+		return out, fmt.Errorf("composer timed out after %s", u.opts.ComposerTimeout)
+	}
+
+	return out, err
+}
+
+// ParseComposerLock parses a composer lock file
+func (u *Updater) parseComposerLock() (ComposerLock, error) {
+	var v = ComposerLock{}
+
+	jsonFile, err := os.Open(u.composerLockFile)
+	if err != nil {
+		return v, err
+	}
+
+	b, err := io.ReadAll(jsonFile)
+	if err != nil {
+		return v, err
+	}
+
+	if err := jsonFile.Close(); err != nil {
+		return v, err
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, err
+	}
+
+	v.Checksum, err = fileHash(u.composerLockFile)
+	if err != nil {
+		return v, err
+	}
+
+	return v, nil
+}
+
+// CompareDiffs will return a ComposerDiff struct for parsing
+func (u *Updater) compareDiffs(pre, post ComposerLock) ComposerDiff {
+	var preLookup = make(map[string]Package)
+
+	var diff = ComposerDiff{}
+	diff.Checksum = post.Checksum
+
+	for _, p := range pre.Packages {
+		preLookup[p.Name] = p
+	}
+
+	for _, p := range pre.PackagesDev {
+		preLookup[p.Name] = p
+	}
+
+	newPackages := append(post.Packages, post.PackagesDev...)
+
+	for _, post := range newPackages {
+		pre, ok := preLookup[post.Name]
+		dp := ComposerDiffPackage{}
+		dp.Name = post.Name
+		dp.PostVersion = post.Version
+		dp.URL = repoURL(post.Source.URL)
+
+		if ok {
+			// new version
+			if pre.Version != post.Version {
+				dp.PreVersion = pre.Version
+				dp.CompareURL = compareURL(post.Source.URL, pre.Version, post.Version)
+				diff.Packages = append(diff.Packages, dp)
+			}
+		} else {
+			// new package
+			diff.Packages = append(diff.Packages, dp)
+		}
+
+		delete(preLookup, pre.Name)
+	}
+
+	// deleted packages
+	for _, del := range preLookup {
+		dp := ComposerDiffPackage{}
+		dp.Name = del.Name
+		dp.PreVersion = del.Version
+		dp.URL = repoURL(del.Source.URL)
+		diff.Packages = append(diff.Packages, dp)
+	}
+
+	// we will add to this if there are packages
+	diff.CommitMessage = u.opts.GitCommitTitle
+
+	if len(diff.Packages) == 0 {
+		return diff
+	}
+
+	// generate markdown description
+	description := "## Updated Composer Packages\n\n"
+	description += "Checksum: " + diff.Checksum
+	description += "\n\n### Changes\n\n"
+	for _, p := range diff.Packages {
+		name := fmt.Sprintf("- [%s](%s): ", p.Name, p.URL)
+		version := fmt.Sprintf("`%s...REMOVED`\n", p.PreVersion)
+		if p.PreVersion != "" && p.PostVersion != "" {
+			if p.CompareURL != "" {
+				version = fmt.Sprintf("[`%s...%s`](%s)\n", p.PreVersion, p.PostVersion, p.CompareURL)
+			} else {
+				version = fmt.Sprintf("`%s...%s`\n", p.PreVersion, p.PostVersion)
+			}
+		} else if p.PostVersion != "" {
+			version = fmt.Sprintf("`NEW...%s`\n", p.PostVersion)
+		}
+		description += name + version
+	}
+	diff.Description = description
+
+	// append to the git commit message
+	diff.CommitMessage += "\n"
+	for _, p := range diff.Packages {
+		version := fmt.Sprintf("%s...REMOVED", p.PreVersion)
+		if p.PreVersion != "" && p.PostVersion != "" {
+			version = fmt.Sprintf("%s...%s", p.PreVersion, p.PostVersion)
+		} else if p.PostVersion != "" {
+			version = fmt.Sprintf("NEW...%s", p.PostVersion)
+		}
+		diff.CommitMessage += "\n" + p.Name + ": " + version
+	}
+
+	return diff
+}
+
+func repoURL(uri string) string {
+	uri = strings.TrimSuffix(uri, ".git")
+
+	var re = regexp.MustCompile(`^git@`)
+	uri = re.ReplaceAllString(uri, "https://")
+
+	return uri
+}
+
+func compareURL(uri, pre, post string) string {
+	uri = strings.TrimSuffix(uri, ".git")
+
+	var re = regexp.MustCompile(`^git@`)
+	uri = re.ReplaceAllString(uri, "https://")
+
+	if strings.HasPrefix(uri, "https://github.com/") {
+		uri = fmt.Sprintf("%s/compare/%s...%s", strings.TrimRight(uri, "/"), url.QueryEscape(pre), url.QueryEscape(post))
+	} else if strings.HasPrefix(uri, "https://gitlab.") {
+		uri = fmt.Sprintf("%s/compare/%s...%s", strings.TrimRight(uri, "/"), url.QueryEscape(pre), url.QueryEscape(post))
+	} else if strings.HasPrefix(uri, "https://bitbucket.") {
+		uri = fmt.Sprintf("%s/branches/compare/%s%%0D%s", strings.TrimRight(uri, "/"), url.QueryEscape(pre), url.QueryEscape(post))
+	} else {
+		return ""
+	}
+
+	return uri
+}
+
+// ParseComposerProblems returns the problems of a composer dependency
+// resolution failure, or nil if the output is not a resolution failure
+func parseComposerProblems(output string) []ComposerProblem {
+	if !strings.Contains(output, "could not be resolved") {
+		return nil
+	}
+
+	problems := []ComposerProblem{}
+	var problem *ComposerProblem
+
+	for _, line := range strings.Split(output, "\n") {
+		if problemRe.MatchString(line) {
+			problems = append(problems, ComposerProblem{})
+			problem = &problems[len(problems)-1]
+			continue
+		}
+
+		if problem == nil {
+			continue
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			// a blank line ends the problem list
+			problem = nil
+			continue
+		}
+
+		if !strings.HasPrefix(line, "- ") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "- ")
+		problem.Lines = append(problem.Lines, line)
+
+		for _, name := range packageNameRe.FindAllString(line, -1) {
+			if !slices.Contains(problem.Packages, name) {
+				problem.Packages = append(problem.Packages, name)
+			}
+		}
+	}
+
+	return problems
+}
+
+// PackageList returns the markdown list of package updates
+func packageList(packages []ComposerDiffPackage) string {
+	list := ""
+	for _, p := range packages {
+		version := fmt.Sprintf("`%s...%s`", p.PreVersion, p.PostVersion)
+		if p.CompareURL != "" {
+			version = fmt.Sprintf("[%s](%s)", version, p.CompareURL)
+		}
+		list += fmt.Sprintf("- [%s](%s): %s\n", p.Name, p.URL, version)
+	}
+
+	return list
+}
@@ -0,0 +1,242 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ConfigFile is the default repository configuration file name
+const ConfigFile = ".composer-update-mr.json"
+
+// restrictedConfigKeys can only be set with flags or environment variables, as the
+// API token must never be sent to (or pushed to) a host or project set by a repository
+var restrictedConfigKeys = []string{"api_url", "project", "remote"}
+
+// Config is the (optional) repository configuration file, allowing each
+// project to override the options. Unset values leave the options unchanged.
+type Config struct {
+	// Enabled set to false disables updates for the repository
+	Enabled *bool `json:"enabled"`
+
+	// Branch is the source branch to update & create the merge request for
+	Branch *string `json:"branch"`
+
+	// Branches are the target branches, each with an optional update policy
+	Branches []TargetBranch `json:"branches"`
+
+	// UpdatePolicy is "full" or "security"
+	UpdatePolicy *string `json:"update_policy"`
+
+	ComposerVersion *int     `json:"composer_version"`
+	ComposerFlags   []string `json:"composer_flags"`
+
+	// ComposerTimeout is a duration, eg: "10m"
+	ComposerTimeout     *string  `json:"composer_timeout"`
+	ComposerRetries     *int     `json:"composer_retries"`
+	ComposerMemoryLimit *string  `json:"composer_memory_limit"`
+	PHP                 *string  `json:"php"`
+	PHPIni              []string `json:"php_ini"`
+	PlatformPHP         *string  `json:"platform_php"`
+	PHPVersions         []string `json:"php_versions"`
+	Validate            []string `json:"validate"`
+	ValidatePolicy      *string  `json:"validate_policy"`
+	Verify              []string `json:"verify"`
+	VerifyPolicy        *string  `json:"verify_policy"`
+	PreUpdate           []string `json:"pre_update"`
+	PostUpdate          []string `json:"post_update"`
+	CommitPaths         []string `json:"commit_paths"`
+	UnexpectedFiles     *string  `json:"unexpected_files"`
+	SecretPatterns      []string `json:"secret_patterns"`
+	Bisect              *string  `json:"bisect"`
+	MinReleaseAge       *int     `json:"min_release_age"`
+	Schedule            []string `json:"schedule"`
+	Groups              []Group  `json:"groups"`
+	MaxOpenMRs          *int     `json:"max_open_mrs"`
+	MRMode              *string  `json:"mr_mode"`
+
+	BranchPrefix    *string  `json:"branch_prefix"`
+	BranchName      *string  `json:"branch_name"`
+	CommitTitle     *string  `json:"commit_title"`
+	MRTitlePrefix   *string  `json:"mr_title_prefix"`
+	Labels          []string `json:"labels"`
+	Assignees       []string `json:"assignees"`
+	Reviewers       []string `json:"reviewers"`
+	RotateReviewers *bool    `json:"reviewers_rotate"`
+	CodeOwners      *bool    `json:"codeowners"`
+	ReplaceOpen     *bool    `json:"replace_open"`
+	Rebase          *string  `json:"rebase"`
+	RebaseLabel     *string  `json:"rebase_label"`
+	FailureIssue    *bool    `json:"failure_issue"`
+}
+
+// ReadConfig reads a repository configuration file. A missing file
+// returns nil without an error.
+func ReadConfig(file string) (*Config, error) {
+	b, err := os.ReadFile(filepath.Clean(file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+
+	for _, key := range restrictedConfigKeys {
+		if _, ok := keys[key]; ok {
+			return nil, fmt.Errorf("%s: %s can only be set with a flag or environment variable", filepath.Base(file), key)
+		}
+	}
+
+	c := &Config{}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+
+	return c, nil
+}
+
+// Apply overrides the options with all values set in the configuration file
+func (c *Config) Apply(o *Options) error {
+	if c.ComposerTimeout != nil {
+		d, err := time.ParseDuration(*c.ComposerTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid composer_timeout: %w", err)
+		}
+		o.ComposerTimeout = d
+	}
+
+	setString(&o.GitBranch, c.Branch)
+	setString(&o.UpdatePolicy, c.UpdatePolicy)
+	setString(&o.ComposerMemoryLimit, c.ComposerMemoryLimit)
+	setString(&o.PHPPath, c.PHP)
+	setString(&o.PlatformPHP, c.PlatformPHP)
+	setString(&o.ValidatePolicy, c.ValidatePolicy)
+	setString(&o.VerifyPolicy, c.VerifyPolicy)
+	setString(&o.UnexpectedFiles, c.UnexpectedFiles)
+	setString(&o.Bisect, c.Bisect)
+	setString(&o.MRMode, c.MRMode)
+	setString(&o.BranchPrefix, c.BranchPrefix)
+	setString(&o.BranchName, c.BranchName)
+	setString(&o.GitCommitTitle, c.CommitTitle)
+	setString(&o.MRTitlePrefix, c.MRTitlePrefix)
+	setString(&o.Rebase, c.Rebase)
+	setString(&o.RebaseLabel, c.RebaseLabel)
+	setBool(&o.RotateReviewers, c.RotateReviewers)
+	setBool(&o.CodeOwners, c.CodeOwners)
+	setBool(&o.ReplaceOpen, c.ReplaceOpen)
+	setBool(&o.FailureIssue, c.FailureIssue)
+
+	if c.Branches != nil {
+		o.Branches = c.Branches
+		if len(c.Branches) > 0 {
+			o.GitBranch = c.Branches[0].Name
+		}
+	}
+	if c.ComposerVersion != nil {
+		o.ComposerVersion = *c.ComposerVersion
+	}
+	if c.ComposerFlags != nil {
+		o.ComposerFlags = c.ComposerFlags
+	}
+	if c.ComposerRetries != nil {
+		o.ComposerRetries = *c.ComposerRetries
+	}
+	if c.MinReleaseAge != nil {
+		o.MinReleaseAge = *c.MinReleaseAge
+	}
+	if c.Schedule != nil {
+		o.Schedule = c.Schedule
+	}
+	if c.Groups != nil {
+		o.Groups = c.Groups
+	}
+	if c.MaxOpenMRs != nil {
+		o.MaxOpenMRs = *c.MaxOpenMRs
+	}
+	if c.PHPIni != nil {
+		o.PHPIni = c.PHPIni
+	}
+	if c.Verify != nil {
+		o.Verify = c.Verify
+	}
+	if c.PreUpdate != nil {
+		o.PreUpdate = c.PreUpdate
+	}
+	if c.PostUpdate != nil {
+		o.PostUpdate = c.PostUpdate
+	}
+	if c.CommitPaths != nil {
+		o.CommitPaths = trimSlice(c.CommitPaths)
+	}
+	if c.SecretPatterns != nil {
+		o.SecretPatterns = c.SecretPatterns
+	}
+	if c.Validate != nil {
+		o.Validate = c.Validate
+	}
+	if c.PHPVersions != nil {
+		o.PHPVersions = c.PHPVersions
+	}
+	if c.Labels != nil {
+		o.Labels = c.Labels
+	}
+	if c.Assignees != nil {
+		o.Assignees = c.Assignees
+	}
+	if c.Reviewers != nil {
+		o.Reviewers = c.Reviewers
+	}
+
+	return nil
+}
+
+// IsEnabled returns whether updates are enabled for the repository
+func (c *Config) IsEnabled() bool {
+	return c == nil || c.Enabled == nil || *c.Enabled
+}
+
+func setString(dst *string, v *string) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func setBool(dst *bool, v *bool) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// loadConfig reads & applies the repository configuration file (if any)
+func (u *Updater) loadConfig() (*Config, error) {
+	if u.opts.ConfigFile == "" {
+		return nil, nil
+	}
+
+	file := u.opts.ConfigFile
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(u.opts.RepoDir, file)
+	}
+
+	c, err := ReadConfig(file)
+	if err != nil || c == nil {
+		return nil, err
+	}
+
+	if err := c.Apply(&u.opts); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,202 @@
+package updater
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// version is a normalized version with 4 numeric parts, eg: 8.1.2.0
+type version [4]int
+
+// maxVersionPart is the highest part of a version, eg: the highest patch release
+const maxVersionPart = 1<<31 - 1
+
+var (
+	orSplitRe  = regexp.MustCompile(`\s*\|\|?\s*`)
+	andSplitRe = regexp.MustCompile(`\s*,\s*|\s+`)
+	// operators may be followed by whitespace, eg: ">= 8.1"
+	operatorSpaceRe = regexp.MustCompile(`([<>=!~^]+)\s+`)
+	hyphenRangeRe   = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+	atomRe          = regexp.MustCompile(`^(\^|~|>=|<=|<>|!=|==|=|>|<)?v?([0-9]+(?:\.[0-9x*]+){0,3})(?:[-@].*)?$`)
+)
+
+// parseVersion returns a normalized version, and the number of parts given
+// (excluding wildcards). Stability suffixes (eg: -dev, -beta1) are ignored.
+func parseVersion(s string) (version, int, error) {
+	v := version{}
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-@+"); i > 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 4 {
+		return v, 0, fmt.Errorf("invalid version \"%s\"", s)
+	}
+
+	n := 0
+	for i, p := range parts {
+		if p == "*" || p == "x" {
+			break
+		}
+		num, err := strconv.Atoi(p)
+		if err != nil {
+			return v, 0, fmt.Errorf("invalid version \"%s\"", s)
+		}
+		v[i] = num
+		n++
+	}
+
+	return v, n, nil
+}
+
+// compare returns -1, 0 or 1 if the version is lower, equal or higher than o
+func (v version) compare(o version) int {
+	for i := range v {
+		if v[i] < o[i] {
+			return -1
+		}
+		if v[i] > o[i] {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// bump returns the lowest version higher than all versions matching the
+// first n parts, eg: 8.1 bumped at 2 parts is 8.2
+func (v version) bump(n int) version {
+	b := version{}
+	for i := 0; i < n-1; i++ {
+		b[i] = v[i]
+	}
+	if n > 0 {
+		b[n-1] = v[n-1] + 1
+	}
+
+	return b
+}
+
+// constraintMatches returns whether a version satisfies a composer version
+// constraint, eg: "^7.4 || ~8.0.0", ">=8.1 <8.4" or "8.*". A partial version
+// (eg: PHP 8.1) is compared as its highest patch release.
+func constraintMatches(constraint, ver string) (bool, error) {
+	v, n, err := parseVersion(ver)
+	if err != nil {
+		return false, err
+	}
+
+	for i := n; i < 3; i++ {
+		v[i] = maxVersionPart
+	}
+
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" {
+		return true, nil
+	}
+
+	for _, or := range orSplitRe.Split(constraint, -1) {
+		ok, err := andMatches(or, v)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// andMatches returns whether a version satisfies all constraints of a
+// (space or comma-separated) constraint list
+func andMatches(constraint string, v version) (bool, error) {
+	// hyphen range, eg: "8.0 - 8.2"
+	if match := hyphenRangeRe.FindStringSubmatch(constraint); match != nil {
+		from, _, err := parseVersion(match[1])
+		if err != nil {
+			return false, err
+		}
+		to, n, err := parseVersion(match[2])
+		if err != nil {
+			return false, err
+		}
+		if n < 4 {
+			// a partial upper bound includes all its versions
+			return v.compare(from) >= 0 && v.compare(to.bump(n)) < 0, nil
+		}
+
+		return v.compare(from) >= 0 && v.compare(to) <= 0, nil
+	}
+
+	constraint = operatorSpaceRe.ReplaceAllString(constraint, "$1")
+
+	for _, atom := range andSplitRe.Split(constraint, -1) {
+		ok, err := atomMatches(atom, v)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// atomMatches returns whether a version satisfies a single constraint, eg: ">=8.1"
+func atomMatches(atom string, v version) (bool, error) {
+	if atom == "" || atom == "*" || strings.HasPrefix(atom, "@") {
+		return true, nil
+	}
+
+	match := atomRe.FindStringSubmatch(atom)
+	if match == nil {
+		return false, fmt.Errorf("invalid constraint \"%s\"", atom)
+	}
+
+	c, n, err := parseVersion(match[2])
+	if err != nil {
+		return false, err
+	}
+
+	wildcard := strings.ContainsAny(match[2], "x*")
+
+	switch match[1] {
+	case "^":
+		// the first non-zero part may not change, eg: ^0.3 is < 0.4
+		upper := n
+		for i := 0; i < n; i++ {
+			if c[i] != 0 || i == n-1 {
+				upper = i + 1
+				break
+			}
+		}
+		return v.compare(c) >= 0 && v.compare(c.bump(upper)) < 0, nil
+	case "~":
+		upper := n - 1
+		if upper < 1 {
+			upper = 1
+		}
+		return v.compare(c) >= 0 && v.compare(c.bump(upper)) < 0, nil
+	case ">=":
+		return v.compare(c) >= 0, nil
+	case "<=":
+		return v.compare(c) <= 0, nil
+	case ">":
+		return v.compare(c) > 0, nil
+	case "<":
+		return v.compare(c) < 0, nil
+	case "!=", "<>":
+		if wildcard {
+			return v.compare(c) < 0 || v.compare(c.bump(n)) >= 0, nil
+		}
+		return v.compare(c) != 0, nil
+	default:
+		if wildcard {
+			return v.compare(c) >= 0 && v.compare(c.bump(n)) < 0, nil
+		}
+		return v.compare(c) == 0, nil
+	}
+}
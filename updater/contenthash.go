@@ -0,0 +1,203 @@
+package updater
+
+import (
+	"bytes"
+	"crypto/md5" // #nosec - used by composer, not for security
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// contentHashKeys are the composer.json keys included in the lock file content-hash
+var contentHashKeys = []string{
+	"name", "version", "require", "require-dev", "conflict", "replace", "provide",
+	"minimum-stability", "prefer-stable", "repositories", "extra",
+}
+
+// jsonMember is a key & value of an ordered JSON object
+type jsonMember struct {
+	Key   string
+	Value interface{}
+}
+
+// jsonObject is a JSON object retaining the key order
+type jsonObject []jsonMember
+
+// composerContentHash returns the content-hash of composer.json contents,
+// as calculated by composer for the lock file
+func composerContentHash(composerJSON []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(composerJSON))
+	dec.UseNumber()
+
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return "", err
+	}
+
+	content, ok := v.(jsonObject)
+	if !ok {
+		return "", fmt.Errorf("composer.json is not a JSON object")
+	}
+
+	relevant := jsonObject{}
+	for _, m := range content {
+		for _, key := range contentHashKeys {
+			if m.Key == key {
+				relevant = append(relevant, m)
+			}
+		}
+
+		if m.Key == "config" {
+			if config, ok := m.Value.(jsonObject); ok {
+				for _, c := range config {
+					if c.Key == "platform" {
+						relevant = append(relevant, jsonMember{Key: "config", Value: jsonObject{c}})
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(relevant, func(i, j int) bool { return relevant[i].Key < relevant[j].Key })
+
+	var b strings.Builder
+	encodePHP(&b, relevant)
+
+	sum := md5.Sum([]byte(b.String())) // #nosec
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// decodeOrdered decodes the next JSON value, retaining the key order of objects
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("unexpected end of JSON")
+		}
+		return nil, err
+	}
+
+	switch t {
+	case json.Delim('{'):
+		obj := jsonObject{}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			key := kt.(string)
+			// duplicate keys are overwritten by PHP, keeping their original position
+			replaced := false
+			for i := range obj {
+				if obj[i].Key == key {
+					obj[i].Value = v
+					replaced = true
+				}
+			}
+			if !replaced {
+				obj = append(obj, jsonMember{Key: key, Value: v})
+			}
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+
+	return t, nil
+}
+
+// encodePHP encodes a value as PHP's json_encode() without any flags, ie: escaping
+// slashes & unicode. Empty objects are encoded as arrays as composer decodes
+// JSON to associative arrays.
+func encodePHP(b *strings.Builder, v interface{}) {
+	switch val := v.(type) {
+	case jsonObject:
+		if len(val) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("{")
+		for i, m := range val {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			encodePHPString(b, m.Key)
+			b.WriteString(":")
+			encodePHP(b, m.Value)
+		}
+		b.WriteString("}")
+	case []interface{}:
+		b.WriteString("[")
+		for i, item := range val {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			encodePHP(b, item)
+		}
+		b.WriteString("]")
+	case string:
+		encodePHPString(b, val)
+	case json.Number:
+		b.WriteString(val.String())
+	case bool:
+		if val {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	default:
+		b.WriteString("null")
+	}
+}
+
+// encodePHPString encodes a string as PHP's json_encode()
+func encodePHPString(b *strings.Builder, s string) {
+	b.WriteString(`"`)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '/':
+			b.WriteString(`\/`)
+		case r == '\b':
+			b.WriteString(`\b`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || (r >= 0x80 && r < 0x10000):
+			fmt.Fprintf(b, `\u%04x`, r)
+		case r >= 0x10000:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(b, `\u%04x\u%04x`, r1, r2)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(`"`)
+}
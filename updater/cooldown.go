@@ -0,0 +1,236 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// releaseTimeFormats are the formats of the package release time in composer.lock
+var releaseTimeFormats = []string{time.RFC3339, "2006-01-02 15:04:05"}
+
+// HeldBackPackage is an updated package version which was held back as it
+// is younger than the minimum release age
+type HeldBackPackage struct {
+	// Name is the package name
+	Name string
+	// Version is the held back version
+	Version string
+	// Released is the release date of the version
+	Released time.Time
+	// Eligible is the date the version is old enough to be proposed
+	Eligible time.Time
+	// Requires is the held back release required by the version, when
+	// the version is held back as it cannot be installed without it
+	Requires string
+}
+
+// ParseReleaseTime parses the release time of a package in composer.lock
+func parseReleaseTime(s string) (time.Time, error) {
+	for _, format := range releaseTimeFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid release time \"%s\"", s)
+}
+
+// PackageReleaseTime returns the release time of a package version from the package
+// metadata of the repositories, for lock files without the release time
+func (u *Updater) packageReleaseTime(name, version string) (time.Time, error) {
+	out, err := u.composer("show", "--all", "--format=json", "--no-interaction", name, version)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	info := struct {
+		// Released is the release time of the version (composer >= 2.5)
+		Released string `json:"released"`
+	}{}
+
+	start, end := strings.Index(out, "{"), strings.LastIndex(out, "}")
+	if start < 0 || end < start {
+		return time.Time{}, fmt.Errorf("invalid composer show output")
+	}
+
+	if err := json.Unmarshal([]byte(out[start:end+1]), &info); err != nil {
+		return time.Time{}, fmt.Errorf("invalid composer show output: %w", err)
+	}
+
+	if info.Released == "" {
+		return time.Time{}, fmt.Errorf("no release time")
+	}
+
+	return parseReleaseTime(info.Released)
+}
+
+// HoldBackRecentReleases re-runs composer with the packages updated to a version
+// younger than the minimum release age excluded, returning the held back versions.
+// The release time is the time of the package in composer.lock, or else the release
+// time of the package metadata, and packages without either are never held back.
+// When the remaining packages cannot be updated, the packages requiring a held
+// back release are held back too.
+func (u *Updater) holdBackRecentReleases(original []byte, pre, post ComposerLock) ([]HeldBackPackage, error) {
+	held := []HeldBackPackage{}
+	names := []string{}
+	minAge := time.Duration(u.opts.MinReleaseAge) * 24 * time.Hour
+
+	preVersions := map[string]string{}
+	for _, p := range append(append([]Package{}, pre.Packages...), pre.PackagesDev...) {
+		preVersions[p.Name] = p.Version
+	}
+
+	// the release times looked up in the package metadata (name@version)
+	lookups := map[string]time.Time{}
+
+	for {
+		updated := []string{}
+		young := 0
+
+		for _, p := range append(append([]Package{}, post.Packages...), post.PackagesDev...) {
+			if v, ok := preVersions[p.Name]; !ok || v == p.Version {
+				continue
+			}
+
+			released, err := parseReleaseTime(p.Time)
+			if p.Time == "" || err != nil {
+				key := p.Name + "@" + p.Version
+				if _, ok := lookups[key]; !ok {
+					if lookups[key], err = u.packageReleaseTime(p.Name, p.Version); err != nil {
+						u.printf("The release time of %s %s is unknown: %s\n", p.Name, p.Version, err)
+					}
+				}
+				released = lookups[key]
+			}
+
+			if released.IsZero() || time.Since(released) >= minAge {
+				updated = append(updated, p.Name)
+				continue
+			}
+
+			if !slices.Contains(names, p.Name) {
+				names = append(names, p.Name)
+				u.holds[p.Name] = preVersions[p.Name]
+				held = append(held, HeldBackPackage{Name: p.Name, Version: p.Version, Released: released, Eligible: released.Add(minAge)})
+				young++
+			}
+		}
+
+		if young == 0 {
+			break
+		}
+
+		u.printf("\n==========\nHolding back %d releases younger than %d days\n==========\n", young, u.opts.MinReleaseAge)
+
+		sort.Strings(updated)
+
+		for {
+			err := u.partialUpdate(original, updated)
+			if err == nil {
+				break
+			}
+
+			// an eligible version may require a held back release
+			dependents := dependentPackages(post, updated, held)
+			if len(dependents) == 0 {
+				return held, err
+			}
+
+			for _, p := range dependents {
+				names = append(names, p.Name)
+				u.holds[p.Name] = preVersions[p.Name]
+				held = append(held, p)
+				u.printf("Holding back %s %s requiring %s\n", p.Name, p.Version, p.Requires)
+			}
+
+			updated = slices.DeleteFunc(updated, func(name string) bool {
+				return slices.Contains(names, name)
+			})
+		}
+
+		lock, err := u.parseComposerLock()
+		if err != nil {
+			return held, err
+		}
+		post = lock
+	}
+
+	sort.Slice(held, func(i, j int) bool { return held[i].Name < held[j].Name })
+
+	return held, nil
+}
+
+// DependentPackages returns the updated packages (recursively) requiring a held back
+// release, which are held back until the release they require is eligible
+func dependentPackages(lock ComposerLock, updated []string, held []HeldBackPackage) []HeldBackPackage {
+	eligible := map[string]time.Time{}
+	for _, p := range held {
+		eligible[p.Name] = p.Eligible
+	}
+
+	dependents := []HeldBackPackage{}
+	for found := true; found; {
+		found = false
+
+		for _, p := range append(append([]Package{}, lock.Packages...), lock.PackagesDev...) {
+			if _, ok := eligible[p.Name]; ok || !slices.Contains(updated, p.Name) {
+				continue
+			}
+
+			requires := []string{}
+			for name := range p.Require {
+				if _, ok := eligible[name]; ok {
+					requires = append(requires, name)
+				}
+			}
+			if len(requires) == 0 {
+				continue
+			}
+
+			// the version is eligible once all the releases it requires are
+			sort.Strings(requires)
+			d := HeldBackPackage{Name: p.Name, Version: p.Version, Requires: strings.Join(requires, ", ")}
+			d.Released, _ = parseReleaseTime(p.Time)
+			for _, name := range requires {
+				if eligible[name].After(d.Eligible) {
+					d.Eligible = eligible[name]
+				}
+			}
+
+			eligible[p.Name] = d.Eligible
+			dependents = append(dependents, d)
+			found = true
+		}
+	}
+
+	return dependents
+}
+
+// HeldBackReport returns the markdown report of the held back recent
+// releases for the merge request description
+func (u *Updater) heldBackReport(held []HeldBackPackage) string {
+	if len(held) == 0 {
+		return ""
+	}
+
+	report := fmt.Sprintf("\n\n### Recent releases\n\nThe following versions were released less than %d days ago (or require such a release), and are held back until they are eligible:\n\n", u.opts.MinReleaseAge)
+	report += "| Package | Version | Released | Eligible |\n|---|---|---|---|\n"
+
+	for _, p := range held {
+		name, released := p.Name, "-"
+		if p.Requires != "" {
+			name += fmt.Sprintf(" (requires %s)", p.Requires)
+		}
+		if !p.Released.IsZero() {
+			released = p.Released.UTC().Format("2006-01-02 15:04")
+		}
+
+		report += fmt.Sprintf("| %s | `%s` | %s | %s |\n", name, p.Version, released, p.Eligible.UTC().Format("2006-01-02 15:04"))
+	}
+
+	return strings.TrimSuffix(report, "\n")
+}
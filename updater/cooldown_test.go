@@ -0,0 +1,171 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateMinReleaseAge(t *testing.T) {
+	old := time.Now().AddDate(0, 0, -30).UTC().Format(time.RFC3339)
+	recent := time.Now().AddDate(0, 0, -1).UTC()
+
+	lock := func(a, aTime, b, bTime string) string {
+		return fmt.Sprintf(`{
+    "content-hash": "abc",
+    "packages": [
+        {"name": "vendor/a", "version": "%s", "time": "%s"},
+        {"name": "vendor/b", "version": "%s", "time": "%s"}
+    ],
+    "packages-dev": []
+}
+`, a, aTime, b, bTime)
+	}
+
+	pre := lock("1.0.0", old, "1.0.0", old)
+	full := lock("1.1.0", old, "2.0.0", recent.Format(time.RFC3339))
+	safe := lock("1.1.0", old, "1.0.0", old)
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(full)},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress", "vendor/a", "--with=vendor/b:1.0.0"}, Do: writeLock(safe)},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/a: 1.0.0...1.1.0"})...)
+
+	u, server := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.MinReleaseAge = 7
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusCreated || result.Checksum != checksum(safe) {
+		t.Errorf("expected a merge request without vendor/b, got %v %s", result.Status, result.Checksum)
+	}
+
+	if len(result.HeldBack) != 1 || result.HeldBack[0].Name != "vendor/b" || result.HeldBack[0].Version != "2.0.0" {
+		t.Fatalf("expected vendor/b 2.0.0 to be held back, got %+v", result.HeldBack)
+	}
+
+	eligible := recent.AddDate(0, 0, 7).Format("2006-01-02 15:04")
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 1 || !strings.Contains(mrs[0].Description, "| vendor/b | `2.0.0` |") || !strings.Contains(mrs[0].Description, eligible) {
+		t.Fatalf("expected the merge request to list vendor/b eligible on %s, got %v", eligible, mrs)
+	}
+}
+
+func TestUpdateMinReleaseAgePackageMetadata(t *testing.T) {
+	recent := time.Now().AddDate(0, 0, -1).UTC()
+
+	// the lock files have no release time
+	pre := bisectLock("1.0.0", "1.0.0", "1.0.0")
+	full := bisectLock("1.1.0", "2.0.0", "1.0.0")
+	safe := bisectLock("1.1.0", "1.0.0", "1.0.0")
+
+	show := func(name, version string) []string {
+		return []string{"show", "--all", "--format=json", "--no-interaction", name, version}
+	}
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(full)},
+		{Bin: "composer-2", Args: show("vendor/a", "1.1.0"), Output: `{"name": "vendor/a"}`},
+		{Bin: "composer-2", Args: show("vendor/b", "2.0.0"), Output: fmt.Sprintf(`{"name": "vendor/b", "released": "%s"}`, recent.Format(time.RFC3339))},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress", "vendor/a", "--with=vendor/b:1.0.0"}, Do: writeLock(safe)},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/a: 1.0.0...1.1.0"})...)
+
+	u, _ := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.MinReleaseAge = 7
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusCreated || result.Checksum != checksum(safe) {
+		t.Errorf("expected a merge request without vendor/b, got %v %s", result.Status, result.Checksum)
+	}
+
+	if len(result.HeldBack) != 1 || result.HeldBack[0].Name != "vendor/b" || result.HeldBack[0].Released.Format(time.RFC3339) != recent.Format(time.RFC3339) {
+		t.Fatalf("expected vendor/b 2.0.0 to be held back, got %+v", result.HeldBack)
+	}
+}
+
+func TestUpdateMinReleaseAgeDependents(t *testing.T) {
+	old := time.Now().AddDate(0, 0, -30).UTC().Format(time.RFC3339)
+	recent := time.Now().AddDate(0, 0, -1).UTC()
+
+	lock := func(a, aRequires, b, bTime, c string) string {
+		return fmt.Sprintf(`{
+    "content-hash": "abc",
+    "packages": [
+        {"name": "vendor/a", "version": "%s", "require": {"vendor/b": "%s"}, "time": "%s"},
+        {"name": "vendor/b", "version": "%s", "time": "%s"},
+        {"name": "vendor/c", "version": "%s", "time": "%s"}
+    ],
+    "packages-dev": []
+}
+`, a, aRequires, old, b, bTime, c, old)
+	}
+
+	pre := lock("1.0.0", "^1.0", "1.0.0", old, "1.0.0")
+	full := lock("2.0.0", "^2.0", "2.0.0", recent.Format(time.RFC3339), "1.1.0")
+	safe := lock("1.0.0", "^1.0", "1.0.0", old, "1.1.0")
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(full)},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress", "vendor/a", "vendor/c", "--with=vendor/b:1.0.0"}, Output: "Your requirements could not be resolved", Err: fmt.Errorf("exit status 2")},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress", "vendor/c", "--with=vendor/a:1.0.0", "--with=vendor/b:1.0.0"}, Do: writeLock(safe)},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/c: 1.0.0...1.1.0"})...)
+
+	u, server := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.MinReleaseAge = 7
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusCreated || result.Checksum != checksum(safe) {
+		t.Errorf("expected a merge request with vendor/c only, got %v %s", result.Status, result.Checksum)
+	}
+
+	eligible := recent.AddDate(0, 0, 7).Format(time.RFC3339)
+	if len(result.HeldBack) != 2 || result.HeldBack[0].Name != "vendor/a" || result.HeldBack[0].Requires != "vendor/b" ||
+		result.HeldBack[0].Eligible.Format(time.RFC3339) != eligible || result.HeldBack[1].Name != "vendor/b" {
+		t.Fatalf("expected vendor/a to be held back with vendor/b, got %+v", result.HeldBack)
+	}
+
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 1 || !strings.Contains(mrs[0].Description, "| vendor/a (requires vendor/b) | `2.0.0` |") {
+		t.Fatalf("expected the merge request to list vendor/a requiring vendor/b, got %v", mrs)
+	}
+}
@@ -0,0 +1,122 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command is an external command to be executed by a Runner
+type Command struct {
+	// Dir is the working directory
+	Dir string
+	// Bin is the binary path
+	Bin string
+	// Args are the command arguments
+	Args []string
+	// Env are additional environment variables, eg: "KEY=value"
+	Env []string
+	// Secrets removes all inherited environment variables containing any of them
+	Secrets []string
+	// Quiet will not print the output
+	Quiet bool
+	// Output is where the output is printed to, defaults to os.Stdout
+	Output io.Writer
+}
+
+// Runner executes external commands, returning all Stdout & Stderr
+type Runner interface {
+	Run(ctx context.Context, c Command) (string, error)
+}
+
+// ExecRunner is the Runner executing real commands
+type ExecRunner struct{}
+
+// Run will execute an external command, printing (unless quiet)
+// & returning all Stdout & Stderr
+func (ExecRunner) Run(ctx context.Context, c Command) (string, error) {
+	cmd := exec.CommandContext(ctx, c.Bin, c.Args...) // #nosec
+	cmd.Dir = c.Dir
+	// child processes (eg: git or unzip spawned by composer) may keep the
+	// output open after the command is killed when the context is cancelled
+	cmd.WaitDelay = 10 * time.Second
+
+	if len(c.Env) > 0 || len(c.Secrets) > 0 {
+		cmd.Env = append(environWithout(c.Secrets), c.Env...)
+	}
+
+	var output io.Writer = os.Stdout
+	if c.Output != nil {
+		output = c.Output
+	}
+
+	var stdBuffer bytes.Buffer
+	mw := io.MultiWriter(output, &stdBuffer)
+	if c.Quiet {
+		mw = io.MultiWriter(&stdBuffer)
+	}
+
+	cmd.Stdout = mw
+	cmd.Stderr = mw
+
+	if err := cmd.Run(); err != nil {
+		return stdBuffer.String(), err
+	}
+
+	return stdBuffer.String(), nil
+}
+
+// EnvironWithout returns the environment without the variables containing any of the secrets
+func environWithout(secrets []string) []string {
+	env := []string{}
+
+	for _, v := range os.Environ() {
+		_, value, _ := strings.Cut(v, "=")
+
+		found := false
+		for _, s := range secrets {
+			if s != "" && strings.Contains(value, s) {
+				found = true
+			}
+		}
+
+		if !found {
+			env = append(env, v)
+		}
+	}
+
+	return env
+}
+
+// Run will execute an external command within the repository directory
+// printing & returning all Stdout & Stderr
+func (u *Updater) run(bin string, args ...string) (string, error) {
+	c := u.command(bin, args)
+	c.Output = u.opts.Output
+
+	return u.opts.Runner.Run(u.ctx, c)
+}
+
+// RunQuiet will execute an external command within the repository directory
+// returning all Stdout & Stderr
+func (u *Updater) runQuiet(bin string, args ...string) (string, error) {
+	c := u.command(bin, args)
+	c.Quiet = true
+
+	return u.opts.Runner.Run(u.ctx, c)
+}
+
+// Command returns a command run within the repository directory, git commands
+// with the additional git environment
+func (u *Updater) command(bin string, args []string) Command {
+	c := Command{Dir: u.opts.RepoDir, Bin: bin, Args: args}
+	if bin == u.opts.GitPath {
+		c.Env = u.opts.gitEnv
+	}
+
+	return c
+}
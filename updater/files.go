@@ -0,0 +1,106 @@
+package updater
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// CommitPaths are the files always committed with the update, if changed
+var CommitPaths = []string{"composer.json", "composer.lock", "symfony.lock"}
+
+// ChangedFiles returns all modified, deleted & untracked files of the working tree
+func (u *Updater) changedFiles() ([]string, error) {
+	out, err := u.runQuiet(u.opts.GitPath, "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		u.println(out)
+		return nil, err
+	}
+
+	files := []string{}
+	entries := strings.Split(out, "\x00")
+
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		files = append(files, entry[3:])
+
+		// renames & copies are followed by the original path
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+
+	return files, nil
+}
+
+// IsCommitPath returns whether a changed file is allowed to be committed. Commit
+// paths are file names, directories or patterns, eg: "config/", "*.neon".
+func (o *Options) isCommitPath(file string) bool {
+	paths := append(append([]string{}, CommitPaths...), o.CommitPaths...)
+
+	for _, p := range paths {
+		p = strings.TrimPrefix(p, "./")
+
+		if strings.HasSuffix(p, "/") && strings.HasPrefix(file, p) {
+			return true
+		}
+
+		if ok, _ := path.Match(p, file); ok || file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CommitFiles returns the changed files to commit. Other changed files are not
+// committed, and either fail the update or are reported in the merge request
+// description depending on the unexpected files policy.
+func (u *Updater) commitFiles(diff *ComposerDiff, result *Result) ([]string, error) {
+	changed, err := u.changedFiles()
+	if err != nil {
+		return nil, fmt.Errorf("listing changed files: %w", err)
+	}
+
+	files := []string{}
+	unexpected := []string{}
+
+	for _, file := range changed {
+		if u.opts.isCommitPath(file) {
+			files = append(files, file)
+		} else {
+			unexpected = append(unexpected, file)
+		}
+	}
+
+	if len(unexpected) == 0 {
+		return files, nil
+	}
+
+	if u.opts.UnexpectedFiles == "fail" {
+		return nil, fmt.Errorf("unexpected changed files: %s", strings.Join(unexpected, ", "))
+	}
+
+	u.printf("Warning: not committing unexpected changed files: %s\n", strings.Join(unexpected, ", "))
+
+	result.UnexpectedFiles = unexpected
+	diff.Description += unexpectedFilesReport(unexpected)
+
+	return files, nil
+}
+
+// UnexpectedFilesReport returns the markdown warning of the changed files which
+// were not committed for the merge request description
+func unexpectedFilesReport(files []string) string {
+	report := "\n\n### Unexpected changes\n\n:warning: The following files were changed during the update, but were not committed:\n\n"
+
+	for _, file := range files {
+		report += fmt.Sprintf("- `%s`\n", file)
+	}
+
+	return strings.TrimSuffix(report, "\n")
+}
@@ -0,0 +1,69 @@
+package updater
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestIsCommitPath(t *testing.T) {
+	opts := DefaultOptions()
+	opts.CommitPaths = []string{".phpstan-baseline.neon", "config/", "./translations", "*.dist"}
+
+	for file, expected := range map[string]bool{
+		"composer.lock":          true,
+		"symfony.lock":           true,
+		".phpstan-baseline.neon": true,
+		"config/packages/a.yaml": true,
+		"translations/en.yaml":   true,
+		"phpunit.xml.dist":       true,
+		"auth.json":              false,
+		"sub/composer.lock":      false,
+		"translations.yaml":      false,
+	} {
+		if opts.isCommitPath(file) != expected {
+			t.Errorf("%s: expected %v", file, expected)
+		}
+	}
+}
+
+func TestCommitPathsTrimmed(t *testing.T) {
+	t.Setenv("COMPOSER_MR_COMMIT_PATHS", ".phpstan-baseline.neon, config/ ,")
+
+	opts := DefaultOptions()
+	opts.LoadEnv()
+
+	if !reflect.DeepEqual(opts.CommitPaths, []string{".phpstan-baseline.neon", "config/"}) || !opts.isCommitPath("config/packages/a.yaml") {
+		t.Errorf("expected the environment commit paths to be trimmed, got %q", opts.CommitPaths)
+	}
+
+	opts = DefaultOptions()
+	if err := (&Config{CommitPaths: []string{" translations/", ""}}).Apply(&opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(opts.CommitPaths, []string{"translations/"}) || !opts.isCommitPath("translations/en.yaml") {
+		t.Errorf("expected the configured commit paths to be trimmed, got %q", opts.CommitPaths)
+	}
+}
+
+func TestUpdateUnexpectedFilesFail(t *testing.T) {
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+		{Bin: "git", Args: []string{"status", "--porcelain", "-z", "--untracked-files=all"}, Output: " M composer.lock\x00?? auth.json\x00"},
+	}}
+
+	u, server := setup(t, runner, "main")
+	u.opts.UnexpectedFiles = "fail"
+
+	_, err := u.Run(context.Background())
+	if err == nil || err.Error() != "unexpected changed files: auth.json" {
+		t.Errorf("expected the update to fail, got %v", err)
+	}
+
+	if hasCall(runner.Calls, "git", "push") || len(server.MergeRequests) != 0 {
+		t.Error("expected nothing to be pushed")
+	}
+}
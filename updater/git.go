@@ -0,0 +1,213 @@
+package updater
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// SwitchBranch will switch to a branch
+func (u *Updater) switchBranch(branch string) error {
+	u.println("Pulling latest changes from", branch)
+	if out, err := u.runQuiet(u.opts.GitPath, "checkout", branch); err != nil {
+		u.println(out)
+		return err
+	}
+	if out, err := u.runQuiet(u.opts.GitPath, "pull", "--rebase"); err != nil {
+		u.println(out)
+		return err
+	}
+
+	return nil
+}
+
+// FetchBranch fetches a target branch from the remote & checks it out, discarding
+// the changes of the previous update, as CI jobs only fetch the branch of the
+// pipeline. A local branch with commits not on the remote branch is never reset.
+func (u *Updater) fetchBranch(branch string) error {
+	u.println("Fetching", branch)
+	ref := fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, u.opts.Remote, branch)
+	if out, err := u.runQuiet(u.opts.GitPath, "fetch", u.opts.Remote, ref); err != nil {
+		u.println(out)
+		return err
+	}
+
+	local, err := u.runQuiet(u.opts.GitPath, "for-each-ref", "--format=%(objectname)", "refs/heads/"+branch)
+	if err != nil {
+		u.println(local)
+		return err
+	}
+
+	if strings.TrimSpace(local) != "" {
+		out, err := u.runQuiet(u.opts.GitPath, "rev-list", "--count", fmt.Sprintf("refs/remotes/%s/%s..refs/heads/%s", u.opts.Remote, branch, branch))
+		if err != nil {
+			u.println(out)
+			return err
+		}
+
+		if n := strings.TrimSpace(out); n != "0" {
+			return fmt.Errorf("refusing to reset %s: %s commits are not pushed to %s", branch, n, u.opts.Remote)
+		}
+	}
+	if out, err := u.runQuiet(u.opts.GitPath, "checkout", "--force", "-B", branch, "--track", u.opts.Remote+"/"+branch); err != nil {
+		u.println(out)
+		return err
+	}
+
+	return nil
+}
+
+// CheckWorkTree returns an error if tracked files have uncommitted changes, which
+// would be discarded by switching branches
+func (u *Updater) checkWorkTree() error {
+	out, err := u.runQuiet(u.opts.GitPath, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		u.println(out)
+		return err
+	}
+
+	if strings.TrimSpace(out) != "" {
+		return fmt.Errorf("refusing to switch branches with uncommitted changes:\n%s", strings.TrimRight(out, "\n"))
+	}
+
+	return nil
+}
+
+// ResetBranch switches back to the source branch, discarding all changes. The
+// changes are those of the update, as pulling the branch requires a clean work tree.
+func (u *Updater) resetBranch() error {
+	if out, err := u.runQuiet(u.opts.GitPath, "checkout", "--force", u.opts.GitBranch); err != nil {
+		u.println(out)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateMergeBranch (re)creates the merge branch from the current branch using
+// git, committing only the given files, and replacing any existing remote branch
+// as branch names are stable
+func (u *Updater) updateMergeBranch(diff ComposerDiff, branch string, files []string) error {
+	if err := u.gitSetup(); err != nil {
+		return err
+	}
+	if out, err := u.runQuiet(u.opts.GitPath, "checkout", "-B", branch); err != nil {
+		u.println(out)
+		return err
+	}
+	if out, err := u.runQuiet(u.opts.GitPath, append([]string{"add", "--"}, files...)...); err != nil {
+		u.println(out)
+		return err
+	}
+	if err := u.checkStagedSecrets(); err != nil {
+		return err
+	}
+	if out, err := u.runQuiet(u.opts.GitPath, "commit", "-m", diff.CommitMessage); err != nil {
+		u.println(out)
+		return err
+	}
+	if out, err := u.runQuiet(u.opts.GitPath, "push", "--force", u.opts.Remote, branch); err != nil {
+		u.println(out)
+		return err
+	}
+
+	return nil
+}
+
+// GitSetup will set up the local git instance wth the user.name, user.email
+// and update remote url for committing
+func (u *Updater) gitSetup() error {
+	if u.gitIsSetup {
+		return nil
+	}
+	if _, err := u.run(u.opts.GitPath, "config", "user.name", u.opts.GitUser); err != nil {
+		return err
+	}
+
+	if _, err := u.run(u.opts.GitPath, "config", "user.email", u.opts.GitEmail); err != nil {
+		return err
+	}
+
+	if u.opts.Token != "" && u.opts.RepositoryURL != "" {
+		var re = regexp.MustCompile(`^https:\/\/gitlab-ci-token:(.*)@(.*)`)
+
+		match := re.FindStringSubmatch(u.opts.RepositoryURL)
+		if match == nil {
+			return fmt.Errorf("unable to parse repository URL")
+		}
+
+		originURL := fmt.Sprintf("https://gitlab-ci-token:%s@%s",
+			u.opts.Token,
+			match[2],
+		)
+
+		if _, err := u.run(u.opts.GitPath, "remote", "set-url", u.opts.Remote, originURL); err != nil {
+			u.println("Error setting remote")
+			return err
+		}
+	}
+
+	u.gitIsSetup = true
+
+	return nil
+}
+
+// DetectProject sets the API URL & project path from the git remote URL,
+// unless already set
+func (u *Updater) detectProject() error {
+	if u.opts.APIURL != "" && (u.opts.ProjectID != "" || u.opts.ProjectPath != "") {
+		return nil
+	}
+
+	out, err := u.runQuiet(u.opts.GitPath, "remote", "get-url", u.opts.Remote)
+	if err != nil {
+		return fmt.Errorf("unable to get the URL of git remote \"%s\"", u.opts.Remote)
+	}
+
+	apiURL, project, err := parseRemoteURL(strings.TrimSpace(out))
+	if err != nil {
+		return err
+	}
+
+	if u.opts.APIURL == "" {
+		u.opts.APIURL = apiURL
+	}
+
+	if u.opts.ProjectID == "" && u.opts.ProjectPath == "" {
+		u.opts.ProjectPath = project
+	}
+
+	return nil
+}
+
+// ParseRemoteURL returns the GitLab API URL & project path of a git remote URL,
+// eg: git@gitlab.com:group/project.git or https://gitlab.com/group/project.git
+func parseRemoteURL(remote string) (string, string, error) {
+	// scp-like syntax, eg: git@gitlab.com:group/project.git
+	if !strings.Contains(remote, "://") {
+		if i := strings.Index(remote, ":"); i > 0 {
+			remote = "ssh://" + remote[:i] + "/" + remote[i+1:]
+		}
+	}
+
+	u, err := url.Parse(remote)
+	if err != nil || u.Hostname() == "" {
+		return "", "", fmt.Errorf("unable to parse git remote URL")
+	}
+
+	project := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if !strings.Contains(project, "/") {
+		return "", "", fmt.Errorf("unable to detect the project from git remote URL")
+	}
+
+	// the SSH port is unrelated to the web port
+	host := u.Host
+	scheme := u.Scheme
+	if scheme != "http" && scheme != "https" {
+		host = u.Hostname()
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s/api/v4", scheme, host), project, nil
+}
@@ -0,0 +1,200 @@
+package updater
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// IsMREnabled is a simple test to determine whether merge requests are enabled for a project.
+func (u *Updater) isMREnabled() bool {
+	opts := gitlab.ListProjectMergeRequestsOptions{
+		Search: gitlab.String("Just a random string that won't return too many results (*^$%#"),
+	}
+
+	_, _, err := u.client.MergeRequests.ListProjectMergeRequests(u.opts.ProjectID, &opts)
+	if err != nil {
+		u.println("Error listing MRs: ", err)
+		return false
+	}
+
+	return true
+}
+
+// FindMR returns an existing open merge request based on checksum
+// of the content, or nil if none exists
+func (u *Updater) findMR(checksum string) *gitlab.MergeRequest {
+	mrs, err := u.listBotMRs(u.opts.GitBranch)
+	if err != nil {
+		u.println("Error listing MRs: ", err)
+		return nil
+	}
+
+	for _, mr := range mrs {
+		// a bisected merge request is identical to the full update it was bisected from
+		if (mr.Metadata.Checksum == checksum || mr.Metadata.FullChecksum == checksum) && mr.Metadata.Package == "" {
+			return mr.MergeRequest
+		}
+	}
+
+	return nil
+}
+
+// BranchMR returns the open merge request created by this tool from the branch, if any
+func (u *Updater) branchMR(branch string) *gitlab.MergeRequest {
+	mrs, err := u.listBotMRs(u.opts.GitBranch)
+	if err != nil {
+		u.println("Error listing MRs: ", err)
+		return nil
+	}
+
+	for _, mr := range mrs {
+		if mr.SourceBranch == branch && mr.Metadata.Package == "" {
+			return mr.MergeRequest
+		}
+	}
+
+	return nil
+}
+
+// OldMRs returns the outdated open merge requests which will be
+// replaced by the new merge request (if enabled)
+func (u *Updater) oldMRs() ([]*gitlab.MergeRequest, error) {
+	old := []*gitlab.MergeRequest{}
+
+	if !u.opts.ReplaceOpen {
+		return old, nil
+	}
+
+	mrs, err := u.listBotMRs(u.opts.GitBranch)
+	if err != nil {
+		return old, fmt.Errorf("error listing MRs: %s", err)
+	}
+
+	for _, mr := range mrs {
+		// package merge requests are replaced per package
+		if mr.Metadata.Package == "" {
+			old = append(old, mr.MergeRequest)
+		}
+	}
+
+	return old, nil
+}
+
+// CloseOldMRs will close outdated merge requests, adding a note linking to the
+// new merge request, and delete their source branches
+func (u *Updater) closeOldMRs(old []*gitlab.MergeRequest, newMR *gitlab.MergeRequest) error {
+	for _, mr := range old {
+		u.printf("Closing outdated MR !%d (%s)\n", mr.IID, mr.SourceBranch)
+
+		note := fmt.Sprintf("This merge request has been superseded by !%d (%s) and has been closed automatically.", newMR.IID, newMR.WebURL)
+
+		if _, _, err := u.client.Notes.CreateMergeRequestNote(u.opts.ProjectID, mr.IID, &gitlab.CreateMergeRequestNoteOptions{
+			Body: gitlab.Ptr(note),
+		}); err != nil {
+			return fmt.Errorf("error adding note to MR !%d: %s", mr.IID, err)
+		}
+
+		if _, _, err := u.client.MergeRequests.UpdateMergeRequest(u.opts.ProjectID, mr.IID, &gitlab.UpdateMergeRequestOptions{
+			StateEvent: gitlab.Ptr("close"),
+		}); err != nil {
+			return fmt.Errorf("error closing MR !%d: %s", mr.IID, err)
+		}
+
+		if _, err := u.client.Branches.DeleteBranch(u.opts.ProjectID, mr.SourceBranch); err != nil {
+			return fmt.Errorf("error deleting branch %s: %s", mr.SourceBranch, err)
+		}
+	}
+
+	return nil
+}
+
+// SupersededReport returns the markdown report of the old merge requests replaced
+// (& closed) by the created or regenerated merge request
+func supersededReport(superseded []*gitlab.MergeRequest) string {
+	if len(superseded) == 0 {
+		return ""
+	}
+
+	refs := []string{}
+	for _, mr := range superseded {
+		refs = append(refs, fmt.Sprintf("!%d", mr.IID))
+	}
+
+	return "\n\n### Superseded merge requests\n\n" +
+		"This merge request replaces " + strings.Join(refs, ", ") + ", which have been closed."
+}
+
+// CreateMergeRequest will create a merge request for the branch
+// setting the title, description and other options
+func (u *Updater) createMergeRequest(title, description, checksum string) (*gitlab.MergeRequest, error) {
+	labels := gitlab.LabelOptions{}
+	for _, lbl := range u.opts.Labels {
+		labels = append(labels, strings.TrimSpace(lbl))
+	}
+
+	opts := gitlab.CreateMergeRequestOptions{
+		Title:              gitlab.Ptr(title),
+		Description:        gitlab.Ptr(u.mrDescription(description, checksum)),
+		SourceBranch:       gitlab.Ptr(u.mrBranch),
+		TargetBranch:       gitlab.Ptr(u.opts.GitBranch),
+		RemoveSourceBranch: gitlab.Ptr(true),
+		AssigneeIDs:        u.getAssigneeIDS(),
+		ReviewerIDs:        u.getReviewerIDS(),
+		Labels:             &labels,
+	}
+
+	mr, _, err := u.client.MergeRequests.CreateMergeRequest(u.opts.ProjectID, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	u.printf("\n==========\nMerge request #%d created: %s\n", mr.ID, mr.WebURL)
+
+	if len(mr.Labels) > 0 {
+		u.println("Labels:", strings.Join(mr.Labels, ", "))
+	}
+
+	if len(mr.Assignees) > 0 {
+		u.println("Assigned to:")
+		for _, a := range mr.Assignees {
+			u.println("-", a.Username)
+		}
+	}
+
+	if len(mr.Reviewers) > 0 {
+		u.println("Reviewers assigned:")
+		for _, a := range mr.Reviewers {
+			u.println("-", a.Username)
+		}
+	}
+
+	u.println("==========")
+
+	return mr, nil
+}
+
+// GetAssigneeIDS returns a slice of IDs assigned to the new merge request
+func (u *Updater) getAssigneeIDS() *[]int {
+	assigneeIDs := u.resolveUsers(u.opts.Assignees)
+
+	return &assigneeIDs
+}
+
+// GetReviewerIDS returns a slice of IDs assigned to the new merge request
+func (u *Updater) getReviewerIDS() *[]int {
+	reviewers := append([]string{}, u.opts.Reviewers...)
+
+	if u.opts.CodeOwners {
+		reviewers = append(reviewers, u.getCodeOwners()...)
+	}
+
+	reviewerIDs := u.resolveUsers(reviewers)
+
+	if u.opts.RotateReviewers {
+		reviewerIDs = u.rotateReviewer(reviewerIDs)
+	}
+
+	return &reviewerIDs
+}
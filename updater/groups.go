@@ -0,0 +1,224 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Group is a named group of packages with its own update rules
+type Group struct {
+	// Name is the group name, eg: "framework"
+	Name string `json:"name"`
+	// Packages are the package name patterns of the group, eg: "symfony/*"
+	Packages []string `json:"packages"`
+	// Schedule are the schedule rules of the group, see Options.Schedule
+	Schedule []string `json:"schedule"`
+	// MaxOpen is the maximum number of open merge requests of the group, 0 for no limit
+	MaxOpen int `json:"max_open"`
+}
+
+// SkippedGroup is a package group which was not updated as it is outside its schedule
+type SkippedGroup struct {
+	// Name is the group name
+	Name string
+	// Schedule are the schedule rules of the group
+	Schedule []string
+	// Packages are the package updates which were held back
+	Packages []ComposerDiffPackage
+	// Kept are the package updates kept at the version proposed by the
+	// open merge request, so it is not regenerated without them
+	Kept []ComposerDiffPackage
+}
+
+// Matches returns whether a package belongs to the group
+func (g Group) matches(name string) bool {
+	for _, pattern := range g.Packages {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PackageGroup returns the first group matching the package name, if any
+func (u *Updater) packageGroup(name string) *Group {
+	for i, g := range u.opts.Groups {
+		if g.matches(name) {
+			return &u.opts.Groups[i]
+		}
+	}
+
+	return nil
+}
+
+// DiffGroup returns the group of the updated packages if they all belong to
+// the same group, otherwise an empty string
+func (u *Updater) diffGroup(packages []ComposerDiffPackage) string {
+	group := ""
+	for i, p := range packages {
+		name := ""
+		if g := u.packageGroup(p.Name); g != nil {
+			name = g.Name
+		}
+
+		if i > 0 && name != group {
+			return ""
+		}
+		group = name
+	}
+
+	return group
+}
+
+// SkipGroups re-runs composer with the updated packages of all groups which
+// are outside their schedule excluded, returning the skipped groups. Packages
+// of skipped groups keep the version already proposed by the open merge request.
+func (u *Updater) skipGroups(original []byte, pre, post ComposerLock, now time.Time) ([]SkippedGroup, error) {
+	skipped := []SkippedGroup{}
+	updated := []string{}
+
+	for _, p := range u.compareDiffs(pre, post).Packages {
+		// added & removed packages are dependencies, and cannot be updated on their own
+		if p.PreVersion == "" || p.PostVersion == "" {
+			continue
+		}
+
+		g := u.packageGroup(p.Name)
+		if g == nil {
+			updated = append(updated, p.Name)
+			continue
+		}
+
+		ok, err := scheduleMatches(g.Schedule, now)
+		if err != nil {
+			return skipped, fmt.Errorf("group %s: %w", g.Name, err)
+		}
+
+		if ok {
+			updated = append(updated, p.Name)
+			continue
+		}
+
+		found := false
+		for i := range skipped {
+			if skipped[i].Name == g.Name {
+				skipped[i].Packages = append(skipped[i].Packages, p)
+				found = true
+			}
+		}
+
+		if !found {
+			skipped = append(skipped, SkippedGroup{Name: g.Name, Schedule: g.Schedule, Packages: []ComposerDiffPackage{p}})
+		}
+	}
+
+	if len(skipped) == 0 {
+		return skipped, nil
+	}
+
+	proposed, err := u.proposedVersions()
+	if err != nil {
+		u.println("Error reading the versions of the open merge request:", err)
+	}
+
+	preVersions := map[string]string{}
+	for _, p := range append(append([]Package{}, pre.Packages...), pre.PackagesDev...) {
+		preVersions[p.Name] = p.Version
+	}
+
+	for i, g := range skipped {
+		u.printf("Skipping group %s outside its schedule (%s)\n", g.Name, strings.Join(g.Schedule, "; "))
+
+		packages := []ComposerDiffPackage{}
+		for _, p := range g.Packages {
+			v, ok := proposed[p.Name]
+			if !ok || v == preVersions[p.Name] {
+				packages = append(packages, p)
+				u.holds[p.Name] = preVersions[p.Name]
+				continue
+			}
+
+			u.printf("Keeping %s %s of the open merge request\n", p.Name, v)
+			// an inline constraint updates the package to the proposed version
+			updated = append(updated, p.Name+":"+v)
+			p.PostVersion, p.CompareURL = v, ""
+			skipped[i].Kept = append(skipped[i].Kept, p)
+		}
+		skipped[i].Packages = packages
+	}
+
+	sort.Strings(updated)
+
+	return skipped, u.partialUpdate(original, updated)
+}
+
+// ProposedVersions returns the package versions of the composer.lock of the open
+// merge request of the source branch (if any), fetching its branch from the remote
+func (u *Updater) proposedVersions() (map[string]string, error) {
+	versions := map[string]string{}
+
+	mrs, err := u.listBotMRs(u.opts.GitBranch)
+	if err != nil {
+		return versions, err
+	}
+
+	branch := ""
+	for _, mr := range mrs {
+		if mr.Metadata.Package == "" {
+			branch = mr.SourceBranch
+			break
+		}
+	}
+
+	if branch == "" {
+		return versions, nil
+	}
+
+	ref := fmt.Sprintf("%s/%s", u.opts.Remote, branch)
+	if out, err := u.runQuiet(u.opts.GitPath, "fetch", u.opts.Remote, fmt.Sprintf("+refs/heads/%s:refs/remotes/%s", branch, ref)); err != nil {
+		return versions, fmt.Errorf("fetching %s: %s", branch, strings.TrimSpace(out))
+	}
+
+	out, err := u.runQuiet(u.opts.GitPath, "show", ref+":./composer.lock")
+	if err != nil {
+		return versions, fmt.Errorf("reading composer.lock of %s: %s", branch, strings.TrimSpace(out))
+	}
+
+	lock := ComposerLock{}
+	if err := json.Unmarshal([]byte(out), &lock); err != nil {
+		return versions, fmt.Errorf("parsing composer.lock of %s: %w", branch, err)
+	}
+
+	for _, p := range append(append([]Package{}, lock.Packages...), lock.PackagesDev...) {
+		versions[p.Name] = p.Version
+	}
+
+	return versions, nil
+}
+
+// SkippedGroupsReport returns the markdown report of the skipped groups for
+// the merge request description
+func skippedGroupsReport(skipped []SkippedGroup) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+
+	report := "\n\n### Skipped groups\n\nThe following package groups are outside their schedule, and were not updated:\n"
+
+	for _, g := range skipped {
+		report += fmt.Sprintf("\n**%s** (%s):\n\n", g.Name, "`"+strings.Join(g.Schedule, "`, `")+"`")
+		report += packageList(g.Packages)
+
+		if len(g.Kept) > 0 {
+			report += "\nKept at the versions proposed when the group was last updated:\n\n"
+			report += packageList(g.Kept)
+		}
+	}
+
+	return strings.TrimSuffix(report, "\n")
+}
@@ -0,0 +1,54 @@
+package updater
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RunHooks runs the hook commands of a stage ("pre-update" or "post-update") in
+// the repository, stopping at the first failing command. File changes made by the
+// hooks are committed with the update.
+func (u *Updater) runHooks(stage string, commands []string) ([]CheckResult, error) {
+	results := []CheckResult{}
+
+	for _, command := range commands {
+		u.printf("Running %s hook: %s\n", stage, command)
+
+		r := u.commandCheck(command)
+		r.Name = stage
+		results = append(results, r)
+
+		// the output of the hook is already streamed to the job output
+		if !r.Passed {
+			return results, fmt.Errorf("%s hook failed: %s", stage, command)
+		}
+	}
+
+	return results, nil
+}
+
+// HooksReport returns the markdown report of the hooks with their output for
+// the merge request description
+func hooksReport(results []CheckResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+
+	report := "\n\n### Hooks\n\n"
+	report += "| Hook | Command | Duration |\n|---|---|---|\n"
+
+	for _, r := range results {
+		report += fmt.Sprintf("| %s | `%s` | %s |\n", r.Name, strings.ReplaceAll(r.Command, "|", "\\|"), r.Duration.Round(time.Millisecond))
+	}
+
+	for _, r := range results {
+		if strings.TrimSpace(r.Output) == "" {
+			continue
+		}
+
+		report += fmt.Sprintf("\n<details><summary><code>%s</code> output</summary>\n\n```\n%s\n```\n\n</details>\n", r.Command, truncateLines(r.Output, maxCheckOutputLines))
+	}
+
+	return strings.TrimSuffix(report, "\n")
+}
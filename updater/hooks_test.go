@@ -0,0 +1,127 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateHooks(t *testing.T) {
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "sh", Args: []string{"-c", "composer normalize"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+		{Bin: "sh", Args: []string{"-c", "composer bump"}, Output: "./composer.json has been updated (1 changes).", Do: func(c Command) {
+			_ = os.WriteFile(filepath.Join(c.Dir, "composer.json"), []byte(`{"require": {"vendor/pkg": "^1.1"}}`), 0600)
+		}},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/pkg: 1.0.0...1.1.0"}, "composer.json", "composer.lock")...)
+
+	u, server := setup(t, runner, "main")
+	u.opts.PreUpdate = []string{"composer normalize"}
+	u.opts.PostUpdate = []string{"composer bump"}
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if len(result.Hooks) != 2 || result.Hooks[0].Name != "pre-update" || result.Hooks[1].Name != "post-update" {
+		t.Fatalf("unexpected hook results: %+v", result.Hooks)
+	}
+
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 1 {
+		t.Fatalf("expected a merge request to be created, got %d", len(mrs))
+	}
+
+	for _, expected := range []string{"### Hooks", "| post-update | `composer bump` |", "composer.json has been updated"} {
+		if !strings.Contains(mrs[0].Description, expected) {
+			t.Errorf("expected description to contain %q:\n%s", expected, mrs[0].Description)
+		}
+	}
+}
+
+func TestUpdateHookFailure(t *testing.T) {
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+		{Bin: "sh", Args: []string{"-c", "composer normalize --dry-run"}, Output: "composer.json is not normalized\n", Err: fmt.Errorf("exit status 1"), Do: func(c Command) {
+			fmt.Fprint(c.Output, "composer.json is not normalized\n")
+		}},
+	}}
+
+	u, server := setup(t, runner, "main")
+	u.opts.PostUpdate = []string{"composer normalize --dry-run", "composer bump"}
+	out := &bytes.Buffer{}
+	u.opts.Output = out
+
+	_, err := u.Run(context.Background())
+	if err == nil || err.Error() != "post-update hook failed: composer normalize --dry-run" {
+		t.Errorf("expected the hook to fail, got %v", err)
+	}
+
+	if hasCall(runner.Calls, "sh", "-c", "composer bump") || hasCall(runner.Calls, "git", "push") || len(server.MergeRequests) != 0 {
+		t.Error("expected nothing to run or be pushed after the failing hook")
+	}
+
+	if n := strings.Count(out.String(), "composer.json is not normalized"); n != 1 {
+		t.Errorf("expected the hook output to be printed once, got %d times", n)
+	}
+}
+
+func TestUpdatePreUpdateHookLockChanges(t *testing.T) {
+	pre := bisectLock("1.0.0", "1.0.0", "1.0.0")
+	hooked := strings.Replace(pre, `"content-hash": "abc"`, `"content-hash": "hooked"`, 1)
+	onlyA := strings.Replace(bisectLock("1.1.0", "1.0.0", "1.0.0"), `"content-hash": "abc"`, `"content-hash": "hooked"`, 1)
+
+	hook := FakeResponse{Bin: "sh", Args: []string{"-c", "php bin/patch-lock.php"}, Do: writeLock(hooked)}
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		hook,
+		{Bin: "composer-2", Args: bisectUpdate(), Do: writeLock(onlyA)},
+		{Bin: "git", Args: []string{"checkout", "--force", "main"}},
+		hook,
+		{Bin: "composer-2", Args: bisectUpdate("--with-dependencies", "vendor/a"), Do: func(c Command) {
+			// the partial update starts from the lock file changed by the hook
+			if b, _ := os.ReadFile(filepath.Join(c.Dir, "composer.lock")); string(b) != hooked {
+				t.Errorf("expected the partial update to start from the hook changes, got %s", b)
+			}
+			writeLock(onlyA)(c)
+		}},
+		{Bin: "git", Args: []string{"checkout", "--force", "main"}},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/vendor-a", []string{"vendor/a: 1.0.0...1.1.0"})...)
+
+	u, _ := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.MRMode = "package"
+	u.opts.PreUpdate = []string{"php bin/patch-lock.php"}
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusCreated {
+		t.Errorf("expected a merge request, got %v", result.Status)
+	}
+}
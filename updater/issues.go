@@ -0,0 +1,191 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// issueMarkerPrefix identifies the hidden metadata in failure issue descriptions
+const issueMarkerPrefix = mrMarkerPrefix + "-failure"
+
+// maxIssueOutputLines is the number of composer output lines included in failure issues
+const maxIssueOutputLines = 100
+
+const (
+	// issueKindResolution is the issue kind reporting composer resolution failures
+	issueKindResolution = ""
+	// issueKindBisect is the issue kind reporting packages held back by a bisect
+	issueKindBisect = "bisect"
+)
+
+var issueMarkerRe = regexp.MustCompile(`<!-- ` + issueMarkerPrefix + ` (\{.*?\}) -->`)
+
+// FailureIssueTitle returns the title of the failure issue
+func (u *Updater) failureIssueTitle() string {
+	return fmt.Sprintf("Composer update failed: dependencies of %s could not be resolved", u.opts.GitBranch)
+}
+
+// FailureIssueDescription returns the failure issue description including the hidden metadata
+func (u *Updater) failureIssueDescription(problems []ComposerProblem, output string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Composer could not resolve the dependencies of `%s`, so no merge request was created.\n\n", u.opts.GitBranch)
+
+	if u.opts.JobURL != "" {
+		fmt.Fprintf(&b, "Failing job: %s\n\n", u.opts.JobURL)
+	}
+
+	for i, p := range problems {
+		fmt.Fprintf(&b, "### Problem %d\n\n", i+1)
+		for _, line := range p.Lines {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+		if len(p.Packages) > 0 {
+			fmt.Fprintf(&b, "\nPackages involved: `%s`\n", strings.Join(p.Packages, "`, `"))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("<details><summary>Composer output</summary>\n\n```\n")
+	b.WriteString(truncateLines(output, maxIssueOutputLines))
+	b.WriteString("\n```\n\n</details>\n\n")
+	b.WriteString("This issue is updated on every failing run, and closed automatically once composer updates successfully.")
+
+	return u.issueDescription(issueKindResolution, b.String())
+}
+
+// IssueDescription appends the hidden metadata to an issue description
+func (u *Updater) issueDescription(kind, description string) string {
+	meta, _ := json.Marshal(IssueMetadata{TargetBranch: u.opts.GitBranch, Kind: kind})
+
+	return description + fmt.Sprintf("\n\n<!-- %s %s -->\n", issueMarkerPrefix, string(meta))
+}
+
+// FindIssue returns the open issue of the given kind for the source branch, if any
+func (u *Updater) findIssue(kind string) (*gitlab.Issue, error) {
+	opts := gitlab.ListProjectIssuesOptions{
+		State:       gitlab.Ptr("opened"),
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+
+	me, _, err := u.client.Users.CurrentUser()
+	if err == nil {
+		opts.AuthorID = &me.ID
+	}
+
+	for {
+		issues, resp, err := u.client.Issues.ListProjectIssues(u.opts.ProjectID, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			match := issueMarkerRe.FindStringSubmatch(issue.Description)
+			if match == nil {
+				continue
+			}
+
+			meta := IssueMetadata{}
+			if err := json.Unmarshal([]byte(match[1]), &meta); err == nil && meta.TargetBranch == u.opts.GitBranch && meta.Kind == kind {
+				return issue, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	return nil, nil
+}
+
+// ReportFailure opens (or updates the existing) failure issue with the
+// composer resolution problems
+func (u *Updater) reportFailure(problems []ComposerProblem, output string) (*gitlab.Issue, error) {
+	return u.saveIssue(issueKindResolution, u.failureIssueTitle(), u.failureIssueDescription(problems, output))
+}
+
+// SaveIssue opens (or updates the existing) issue of the given kind
+func (u *Updater) saveIssue(kind, title, description string) (*gitlab.Issue, error) {
+	// the description contains the command output
+	description = mask(description, u.opts.Secrets())
+
+	existing, err := u.findIssue(kind)
+	if err != nil {
+		return nil, fmt.Errorf("error listing issues: %s", err)
+	}
+
+	if existing != nil {
+		issue, _, err := u.client.Issues.UpdateIssue(u.opts.ProjectID, existing.IID, &gitlab.UpdateIssueOptions{
+			Title:       gitlab.Ptr(title),
+			Description: gitlab.Ptr(description),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error updating issue #%d: %s", existing.IID, err)
+		}
+
+		u.printf("\n==========\nIssue #%d updated: %s\n==========\n", issue.IID, issue.WebURL)
+
+		return issue, nil
+	}
+
+	labels := gitlab.LabelOptions{}
+	for _, lbl := range u.opts.Labels {
+		labels = append(labels, strings.TrimSpace(lbl))
+	}
+
+	issue, _, err := u.client.Issues.CreateIssue(u.opts.ProjectID, &gitlab.CreateIssueOptions{
+		Title:       gitlab.Ptr(title),
+		Description: gitlab.Ptr(description),
+		Labels:      &labels,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating issue: %s", err)
+	}
+
+	u.printf("\n==========\nIssue #%d created: %s\n==========\n", issue.IID, issue.WebURL)
+
+	return issue, nil
+}
+
+// CloseFailureIssue closes the open failure issue (if any) after composer
+// updated successfully
+func (u *Updater) closeFailureIssue() error {
+	return u.closeIssue(issueKindResolution, fmt.Sprintf("Composer updated the dependencies of `%s` successfully", u.opts.GitBranch))
+}
+
+// CloseIssue closes the open issue of the given kind (if any) with a note
+func (u *Updater) closeIssue(kind, reason string) error {
+	issue, err := u.findIssue(kind)
+	if err != nil || issue == nil {
+		return err
+	}
+
+	note := reason
+	if u.opts.JobURL != "" {
+		note += " in " + u.opts.JobURL
+	}
+	note += ", closing this issue."
+
+	if _, _, err := u.client.Notes.CreateIssueNote(u.opts.ProjectID, issue.IID, &gitlab.CreateIssueNoteOptions{
+		Body: gitlab.Ptr(note),
+	}); err != nil {
+		return fmt.Errorf("error adding note to issue #%d: %s", issue.IID, err)
+	}
+
+	if _, _, err := u.client.Issues.UpdateIssue(u.opts.ProjectID, issue.IID, &gitlab.UpdateIssueOptions{
+		StateEvent: gitlab.Ptr("close"),
+	}); err != nil {
+		return fmt.Errorf("error closing issue #%d: %s", issue.IID, err)
+	}
+
+	u.printf("Closed issue #%d: %s\n", issue.IID, reason)
+
+	return nil
+}
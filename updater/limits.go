@@ -0,0 +1,203 @@
+package updater
+
+import (
+	"sort"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// PendingMR is an update merge request which was not created as the limit of
+// open merge requests was reached
+type PendingMR struct {
+	// Title is the merge request title
+	Title string
+	// Group is the package group of the merge request, if any
+	Group string
+	// Priority is the highest priority update type of the packages
+	Priority UpdateType
+	// Packages are the added, updated & removed packages
+	Packages []ComposerDiffPackage
+}
+
+// mrLimiter tracks the open merge requests created by this tool against the limits
+type mrLimiter struct {
+	max       int
+	open      int
+	groupMax  map[string]int
+	groupOpen map[string]int
+	// counted are the groups of the counted open merge requests (IID => group)
+	counted map[int]string
+}
+
+// mrAllotment decides the new merge requests of multiple target branches within
+// the open merge request limits in order of priority, rather than in branch order
+type mrAllotment struct {
+	// candidates are the new merge requests found in the first pass over the branches
+	candidates []mrCandidate
+	// allotted are the keys of the merge requests which may be created in the
+	// second pass, nil during the first pass
+	allotted map[string]bool
+}
+
+// mrCandidate is a new merge request waiting for the allotment
+type mrCandidate struct {
+	// key identifies the merge request by target branch & package
+	key string
+	// branch is the target branch
+	branch string
+	// pending is the merge request listed if it is not allotted
+	pending PendingMR
+	// replaced are the merge requests closed when it is created
+	replaced []*gitlab.MergeRequest
+}
+
+// LimitsEnabled returns whether the open merge requests are limited
+func (u *Updater) limitsEnabled() bool {
+	if u.opts.MaxOpenMRs > 0 {
+		return true
+	}
+
+	for _, g := range u.opts.Groups {
+		if g.MaxOpen > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewMRLimiter counts the open merge requests of all target branches, excluding
+// the merge requests which will be replaced by the new merge request
+func (u *Updater) newMRLimiter(replaced []*gitlab.MergeRequest) (*mrLimiter, error) {
+	l := &mrLimiter{max: u.opts.MaxOpenMRs, groupMax: map[string]int{}, groupOpen: map[string]int{}, counted: map[int]string{}}
+
+	for _, g := range u.opts.Groups {
+		l.groupMax[g.Name] = g.MaxOpen
+	}
+
+	mrs, err := u.listBotMRs("")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mr := range mrs {
+		isReplaced := false
+		for _, r := range replaced {
+			if r.IID == mr.IID {
+				isReplaced = true
+			}
+		}
+
+		if !isReplaced {
+			l.open++
+			l.groupOpen[mr.Metadata.Group]++
+			l.counted[mr.IID] = mr.Metadata.Group
+		}
+	}
+
+	return l, nil
+}
+
+// Allow returns whether a merge request of the group may be created, counting it if so
+func (l *mrLimiter) allow(group string) bool {
+	if l.max > 0 && l.open >= l.max {
+		return false
+	}
+
+	if max := l.groupMax[group]; group != "" && max > 0 && l.groupOpen[group] >= max {
+		return false
+	}
+
+	l.open++
+	l.groupOpen[group]++
+
+	return true
+}
+
+// AllowReplacing returns whether a merge request of the group may be created when
+// it replaces the given merge requests, counting it (& releasing those) if so
+func (l *mrLimiter) allowReplacing(group string, replaced []*gitlab.MergeRequest) bool {
+	released := map[int]string{}
+	for _, r := range replaced {
+		if g, ok := l.counted[r.IID]; ok {
+			released[r.IID] = g
+			delete(l.counted, r.IID)
+			l.open--
+			l.groupOpen[g]--
+		}
+	}
+
+	if l.allow(group) {
+		return true
+	}
+
+	for iid, g := range released {
+		l.counted[iid] = g
+		l.open++
+		l.groupOpen[g]++
+	}
+
+	return false
+}
+
+// AllotMR returns whether the new merge request may be created when updating multiple
+// target branches, and whether this is decided by the allotment. In the first pass
+// over the branches the merge request is collected as a candidate & not created.
+func (u *Updater) allotMR(pending PendingMR, replaced []*gitlab.MergeRequest) (allowed, decided bool) {
+	if u.allotment == nil {
+		return false, false
+	}
+
+	key := u.opts.GitBranch + "\x00" + u.mrPackage
+
+	if u.allotment.allotted != nil {
+		return u.allotment.allotted[key], true
+	}
+
+	u.allotment.candidates = append(u.allotment.candidates, mrCandidate{
+		key:      key,
+		branch:   u.opts.GitBranch,
+		pending:  pending,
+		replaced: replaced,
+	})
+
+	return false, true
+}
+
+// AllotMRs allots the open merge request limits to the candidates in order of
+// priority (in branch order for equal priorities), returning the allotted keys
+func (u *Updater) allotMRs(candidates []mrCandidate) (map[string]bool, error) {
+	limiter, err := u.newMRLimiter(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := append([]mrCandidate{}, candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].pending.Priority < sorted[j].pending.Priority
+	})
+
+	allotted := map[string]bool{}
+	for _, c := range sorted {
+		if limiter.allowReplacing(c.pending.Group, c.replaced) {
+			allotted[c.key] = true
+		}
+	}
+
+	return allotted, nil
+}
+
+// PrintPending prints the merge requests which were not created due to the limits
+func (u *Updater) printPending(pending []PendingMR) {
+	u.printf("\n==========\nThe open merge request limit is reached, %d pending:\n", len(pending))
+
+	for _, p := range pending {
+		group := ""
+		if p.Group != "" {
+			group = ", group " + p.Group
+		}
+		u.printf("- %s (%s%s)\n", p.Title, p.Priority, group)
+	}
+
+	u.println("==========")
+}
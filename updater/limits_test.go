@@ -0,0 +1,135 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestUpdateTypeOf(t *testing.T) {
+	advisories := map[string][]Advisory{"vendor/insecure": {{AdvisoryID: "PKSA-1"}}}
+
+	tests := []struct {
+		pkg  ComposerDiffPackage
+		want UpdateType
+	}{
+		{ComposerDiffPackage{Name: "vendor/insecure", PreVersion: "1.0.0", PostVersion: "2.0.0"}, UpdateSecurity},
+		{ComposerDiffPackage{Name: "vendor/a", PreVersion: "1.0.0", PostVersion: "1.0.1"}, UpdatePatch},
+		{ComposerDiffPackage{Name: "vendor/a", PreVersion: "v1.0.0", PostVersion: "v1.2.0"}, UpdateMinor},
+		{ComposerDiffPackage{Name: "vendor/a", PreVersion: "1.0.0", PostVersion: "2.0.0"}, UpdateMajor},
+		{ComposerDiffPackage{Name: "vendor/a", PreVersion: "0.3.0", PostVersion: "0.4.0"}, UpdateMajor},
+		{ComposerDiffPackage{Name: "vendor/a", PreVersion: "dev-main", PostVersion: "dev-main"}, UpdateMajor},
+		{ComposerDiffPackage{Name: "vendor/a", PostVersion: "1.0.0"}, UpdatePatch},
+	}
+
+	for _, test := range tests {
+		if got := updateTypeOf(test.pkg, advisories); got != test.want {
+			t.Errorf("%+v: expected %s, got %s", test.pkg, test.want, got)
+		}
+	}
+}
+
+func TestUpdateMRLimit(t *testing.T) {
+	audit := `{"advisories": {"vendor/pkg": [{"advisoryId": "PKSA-1", "packageName": "vendor/pkg", "title": "XSS"}]}, "abandoned": []}`
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"audit", "--locked", "--format=json", "--no-interaction"}, Output: audit, Err: fmt.Errorf("exit status 1")},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+	}}
+
+	u, server := setup(t, runner, "main")
+	u.opts.MaxOpenMRs = 1
+	u.opts.ReplaceOpen = false
+
+	// an update merge request of another target branch counts towards the limit
+	server.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Composer update: 1 package", SourceBranch: "composer-update-release", TargetBranch: "release/1.x",
+		Description: botMRDescription("other"),
+	})
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusPending || len(result.Pending) != 1 || result.Pending[0].Priority != UpdateSecurity {
+		t.Fatalf("expected a pending security merge request, got %v %+v", result.Status, result.Pending)
+	}
+
+	if len(server.OpenMergeRequests()) != 1 || hasCall(runner.Calls, "git", "push") {
+		t.Error("expected no merge request to be created")
+	}
+}
+
+func TestUpdateBranchesMRLimitPriority(t *testing.T) {
+	audit := []string{"audit", "--locked", "--format=json", "--no-interaction"}
+	advisory := `{"advisories": {"vendor/pkg": [{"advisoryId": "PKSA-1", "packageName": "vendor/pkg", "title": "XSS"}]}, "abandoned": []}`
+
+	fetch := func(branch string) []FakeResponse {
+		return []FakeResponse{
+			{Bin: "git", Args: []string{"fetch", "origin", "+refs/heads/" + branch + ":refs/remotes/origin/" + branch}},
+			{Bin: "git", Args: []string{"for-each-ref", "--format=%(objectname)", "refs/heads/" + branch}},
+			{Bin: "git", Args: []string{"checkout", "--force", "-B", branch, "--track", "origin/" + branch}, Do: writeLock(preLock)},
+			{Bin: "git", Args: []string{"checkout", branch}},
+			{Bin: "git", Args: []string{"pull", "--rebase"}},
+		}
+	}
+
+	security := append(fetch("release/2.x"),
+		FakeResponse{Bin: "composer-2", Args: audit, Output: advisory, Err: fmt.Errorf("exit status 1")},
+		FakeResponse{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress", "--with-dependencies", "vendor/pkg"}, Do: writeLock(postLock)},
+	)
+
+	// the minor update of main is found first, but the security update of release/2.x is created
+	responses := []FakeResponse{{Bin: "git", Args: []string{"status", "--porcelain", "--untracked-files=no"}}}
+	responses = append(responses, fetch("main")...)
+	responses = append(responses,
+		FakeResponse{Bin: "composer-2", Args: audit, Output: `{"advisories": [], "abandoned": []}`},
+		FakeResponse{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+	)
+	responses = append(responses, security...)
+	responses = append(responses, security...)
+	responses = append(responses, gitSetupResponses()...)
+	responses = append(responses, commitResponses("composer-update/release/2.x", []string{"vendor/pkg: 1.0.0...1.1.0"})...)
+
+	runner := &FakeRunner{Responses: responses}
+
+	u, server := setup(t, runner, "")
+	u.opts.Branches = []TargetBranch{{Name: "main"}, {Name: "release/2.x", Policy: "security"}}
+	u.opts.MaxOpenMRs = 1
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusCreated || len(result.Branches) != 2 {
+		t.Fatalf("unexpected result: %v %+v", result.Status, result.Branches)
+	}
+
+	main, release := result.Branches[0].Result, result.Branches[1].Result
+	if main.Status != StatusPending || len(main.Pending) != 1 || main.Pending[0].Priority != UpdateMinor {
+		t.Errorf("expected a pending minor update of main, got %v %+v", main.Status, main.Pending)
+	}
+
+	if release.Status != StatusCreated {
+		t.Errorf("expected the security merge request of release/2.x, got %v", release.Status)
+	}
+
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 1 || mrs[0].TargetBranch != "release/2.x" {
+		t.Errorf("expected a single merge request to release/2.x, got %+v", mrs)
+	}
+}
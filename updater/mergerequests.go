@@ -0,0 +1,202 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	// mrMarkerPrefix identifies the hidden metadata in merge request descriptions
+	mrMarkerPrefix = "gitlabci-composer-update-mr"
+
+	// rebaseCheckbox is the marker of the regenerate checkbox in merge request descriptions
+	rebaseCheckbox = "<!-- " + mrMarkerPrefix + "-rebase -->"
+)
+
+var (
+	mrMarkerRe = regexp.MustCompile(`<!-- ` + mrMarkerPrefix + ` (\{.*?\}) -->`)
+
+	rebaseCheckedRe = regexp.MustCompile(`(?i)- \[x\] ` + regexp.QuoteMeta(rebaseCheckbox))
+
+	// legacy merge requests only contained a visible checksum
+	legacyChecksumRe = regexp.MustCompile(`Checksum: ([a-f0-9]+)`)
+)
+
+// BotMR is an open merge request created by this tool
+type botMR struct {
+	*gitlab.MergeRequest
+	Metadata MRMetadata
+}
+
+// MRMarker returns the hidden metadata marker for a merge request description
+func mrMarker(meta MRMetadata) string {
+	b, _ := json.Marshal(meta)
+
+	return fmt.Sprintf("\n\n<!-- %s %s -->\n", mrMarkerPrefix, string(b))
+}
+
+// MRDescription returns the full merge request description including
+// the regenerate checkbox and hidden metadata, with the secrets masked in
+// the command output
+func (u *Updater) mrDescription(description, checksum string) string {
+	description = mask(description, u.opts.Secrets())
+	description += "\n\n---\n\n"
+	description += "- [ ] " + rebaseCheckbox + " If you want to regenerate this merge request, check this box"
+
+	return description + mrMarker(MRMetadata{Checksum: checksum, TargetBranch: u.opts.GitBranch, Group: u.mrGroup, Package: u.mrPackage, FullChecksum: u.fullChecksum})
+}
+
+// ParseMRMetadata returns the metadata of a merge request created by this tool.
+// Merge requests created prior to the hidden marker are identified by their
+// title prefix, labels and visible checksum.
+func (u *Updater) parseMRMetadata(mr *gitlab.MergeRequest) (MRMetadata, bool) {
+	meta := MRMetadata{}
+
+	if match := mrMarkerRe.FindStringSubmatch(mr.Description); match != nil {
+		if err := json.Unmarshal([]byte(match[1]), &meta); err == nil {
+			return meta, true
+		}
+	}
+
+	if !strings.HasPrefix(mr.Title, u.opts.MRTitlePrefix) {
+		return meta, false
+	}
+
+	for _, lbl := range u.opts.Labels {
+		if !hasLabel(mr.Labels, lbl) {
+			return meta, false
+		}
+	}
+
+	match := legacyChecksumRe.FindStringSubmatch(mr.Description)
+	if match == nil {
+		return meta, false
+	}
+
+	meta.Checksum = match[1]
+	meta.TargetBranch = mr.TargetBranch
+
+	return meta, true
+}
+
+// ListBotMRs returns all open merge requests created by this tool for the target
+// branch (all branches if empty), paginating through all the API user's open
+// merge requests
+func (u *Updater) listBotMRs(targetBranch string) ([]botMR, error) {
+	results := []botMR{}
+
+	opts := gitlab.ListProjectMergeRequestsOptions{
+		State:       gitlab.Ptr("opened"),
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+
+	if targetBranch != "" {
+		opts.TargetBranch = gitlab.Ptr(targetBranch)
+	}
+
+	me, _, err := u.client.Users.CurrentUser()
+	if err == nil {
+		opts.AuthorID = &me.ID
+	}
+
+	for {
+		mrs, resp, err := u.client.MergeRequests.ListProjectMergeRequests(u.opts.ProjectID, &opts)
+		if err != nil {
+			return results, err
+		}
+
+		for _, mr := range mrs {
+			if meta, ok := u.parseMRMetadata(mr); ok {
+				results = append(results, botMR{MergeRequest: mr, Metadata: meta})
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	return results, nil
+}
+
+// HasLabel returns whether a label exists (case-insensitive) in a list of labels
+func hasLabel(labels []string, label string) bool {
+	label = strings.ToLower(strings.TrimSpace(label))
+	for _, l := range labels {
+		if strings.ToLower(l) == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RegenerateReason returns why an existing merge request should be regenerated on the
+// latest source branch, or an empty string if it is up to date. Depending on
+// COMPOSER_MR_REBASE this is when the merge request cannot be merged, or when it
+// is behind the source branch. Developers can also force regeneration by checking
+// the checkbox in the description or adding the rebase label.
+func (u *Updater) regenerateReason(mr *gitlab.MergeRequest) string {
+	if rebaseCheckedRe.MatchString(mr.Description) {
+		return "regeneration was requested via the merge request description"
+	}
+
+	if hasLabel(mr.Labels, u.opts.RebaseLabel) {
+		return fmt.Sprintf("regeneration was requested via the \"%s\" label", u.opts.RebaseLabel)
+	}
+
+	mode := u.opts.Rebase
+	if mode == "never" {
+		return ""
+	}
+
+	details, _, err := u.client.MergeRequests.GetMergeRequest(u.opts.ProjectID, mr.IID, &gitlab.GetMergeRequestsOptions{
+		IncludeDivergedCommitsCount: gitlab.Ptr(true),
+	})
+	if err != nil {
+		u.println("Error fetching MR: ", err)
+		return ""
+	}
+
+	if details.HasConflicts || details.MergeStatus == "cannot_be_merged" || details.DetailedMergeStatus == "conflict" {
+		return "the merge request cannot be merged"
+	}
+
+	if mode == "behind" && (details.DivergedCommitsCount > 0 || details.DetailedMergeStatus == "need_rebase") {
+		return fmt.Sprintf("the merge request is %d commit(s) behind %s", details.DivergedCommitsCount, mr.TargetBranch)
+	}
+
+	return ""
+}
+
+// UpdateMergeRequest will update an existing merge request after it has been regenerated,
+// resetting the description & removing the rebase label
+func (u *Updater) updateMergeRequest(mr *gitlab.MergeRequest, title, description, checksum, reason string) error {
+	opts := gitlab.UpdateMergeRequestOptions{
+		Title:        gitlab.Ptr(title),
+		Description:  gitlab.Ptr(u.mrDescription(description, checksum)),
+		RemoveLabels: &gitlab.LabelOptions{u.opts.RebaseLabel},
+	}
+
+	if _, _, err := u.client.MergeRequests.UpdateMergeRequest(u.opts.ProjectID, mr.IID, &opts); err != nil {
+		return fmt.Errorf("error updating MR !%d: %s", mr.IID, err)
+	}
+
+	note := fmt.Sprintf("This merge request has been regenerated on the latest `%s` because %s.", mr.TargetBranch, reason)
+
+	if _, _, err := u.client.Notes.CreateMergeRequestNote(u.opts.ProjectID, mr.IID, &gitlab.CreateMergeRequestNoteOptions{
+		Body: gitlab.Ptr(note),
+	}); err != nil {
+		return fmt.Errorf("error adding note to MR !%d: %s", mr.IID, err)
+	}
+
+	u.printf("\n==========\nMerge request !%d regenerated: %s\n==========\n", mr.IID, mr.WebURL)
+
+	return nil
+}
@@ -0,0 +1,520 @@
+package updater
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configures an Updater
+type Options struct {
+	// GitUser is the git commit username
+	GitUser string
+
+	// GitEmail is the git commit email
+	GitEmail string
+
+	// GitBranch is the source branch to update & create the merge request for
+	GitBranch string
+
+	// Branches are the target branches updated sequentially, each with its own
+	// update policy & merge request. Overrides GitBranch when set.
+	Branches []TargetBranch
+
+	// UpdatePolicy is "full" to update all packages, or "security" to only update
+	// the packages with security advisories (with their dependencies)
+	UpdatePolicy string
+
+	// RepoDir is the directory where the repository is
+	RepoDir string
+
+	// ConfigFile is the repository configuration file, relative to RepoDir.
+	// An empty value disables the configuration file.
+	ConfigFile string
+
+	// ComposerVersion is the composer major version (1 or 2), used to locate
+	// the composer-<version> binary if ComposerPath is not set
+	ComposerVersion int
+
+	// ComposerPath binary path
+	ComposerPath string
+
+	// ComposerFlags composer flags
+	ComposerFlags []string
+
+	// ComposerTimeout is the maximum duration of each composer command, 0 for no timeout
+	ComposerTimeout time.Duration
+
+	// ComposerRetries is the number of times a composer command is retried
+	// after a network error
+	ComposerRetries int
+
+	// ComposerRetryDelay is the delay before the first retry, doubled for every retry
+	ComposerRetryDelay time.Duration
+
+	// ComposerMemoryLimit sets COMPOSER_MEMORY_LIMIT, eg: "-1" or "2G"
+	ComposerMemoryLimit string
+
+	// PlatformPHP is the target PHP version (config.platform.php) to resolve the
+	// dependencies for, eg: "8.1". The platform config is not committed.
+	PlatformPHP string
+
+	// PHPVersions are the PHP versions to check the locked packages against, eg: 8.1, 8.2
+	PHPVersions []string
+
+	// Validate are the post-update validation checks, see ValidationChecks
+	Validate []string
+
+	// ValidatePolicy is "abort" to fail without pushing when a validation check
+	// fails, or "report" to only report the failure in the merge request
+	ValidatePolicy string
+
+	// Verify are shell commands run after the update to verify it, eg: "vendor/bin/phpunit"
+	Verify []string
+
+	// VerifyPolicy is "draft" to mark the merge request as draft when a verify
+	// command fails, or "skip" to not create (or update) the merge request
+	VerifyPolicy string
+
+	// PreUpdate are shell commands run before composer updates, eg: "composer normalize"
+	PreUpdate []string
+
+	// PostUpdate are shell commands run after the update, eg: "composer bump". Their
+	// file changes are committed with the update, and their output is included in
+	// the merge request.
+	PostUpdate []string
+
+	// CommitPaths are the files, directories or patterns committed with the update in
+	// addition to composer.json, composer.lock & symfony.lock, eg: ".phpstan-baseline.neon"
+	CommitPaths []string
+
+	// UnexpectedFiles is "warn" to report changed files outside the commit paths in
+	// the merge request, or "fail" to fail the update. They are never committed.
+	UnexpectedFiles string
+
+	// SecretPatterns are regular expressions of secrets which must never be pushed, in
+	// addition to composer auth, GitLab tokens & private keys
+	SecretPatterns []string
+
+	// Schedule are the schedule rules of the updates, eg: "weekdays before 10:00 Europe/Berlin".
+	// Runs outside all the rules are skipped, no rules allow any time.
+	Schedule []string
+
+	// Groups are the package groups with their own update rules. Each package
+	// belongs to the first matching group.
+	Groups []Group
+
+	// MRMode is "combined" for a single merge request with all updates, or "package"
+	// for a merge request per updated package
+	MRMode string
+
+	// MaxOpenMRs is the maximum number of open merge requests created by this tool
+	// (of all target branches), 0 for no limit. See also Group.MaxOpen.
+	MaxOpenMRs int
+
+	// MinReleaseAge is the minimum age in days of an updated package version. Younger
+	// versions are held back until they are eligible, 0 to disable.
+	MinReleaseAge int
+
+	// Bisect is a test command run after the update, eg: "vendor/bin/phpunit". When it
+	// fails, the updated packages are bisected to find the package(s) breaking it,
+	// which are held back from the merge request & reported in an issue.
+	Bisect string
+
+	// PHPPath is the PHP binary to run composer with. If not set (and
+	// PHPIni is not set) composer is executed directly.
+	PHPPath string
+
+	// PHPIni are PHP ini overrides passed to PHP with -d, eg: "memory_limit=-1"
+	PHPIni []string
+
+	// GitPath binary path, located in $PATH if not set
+	GitPath string
+
+	// BranchPrefix is prepended to the merge request branch name, eg: "feature/"
+	BranchPrefix string
+
+	// BranchName is the merge request branch name template, with the variables {target},
+	// {group}, {checksum}, {date} & {package}. Defaults to DefaultBranchName, or
+	// DefaultPackageBranchName in package mode.
+	BranchName string
+
+	// GitCommitTitle is the first line of the git commit message
+	GitCommitTitle string
+
+	// MRTitlePrefix is the first part of the merge request title
+	MRTitlePrefix string
+
+	// Labels are added to the merge request
+	Labels []string
+
+	// Assignees are the merge request assignees (usernames, emails, IDs or @groups)
+	Assignees []string
+
+	// Reviewers are the merge request reviewers (usernames, emails, IDs or @groups)
+	Reviewers []string
+
+	// RotateReviewers assigns a single reviewer, round-robin
+	RotateReviewers bool
+
+	// CodeOwners adds the CODEOWNERS of the composer files as reviewers
+	CodeOwners bool
+
+	// ReplaceOpen closes outdated open merge requests
+	ReplaceOpen bool
+
+	// Rebase sets when existing merge requests are regenerated: "conflicted", "behind" or "never"
+	Rebase string
+
+	// RebaseLabel is the label to force regeneration of an existing merge request
+	RebaseLabel string
+
+	// FailureIssue opens an issue when composer cannot resolve the dependencies,
+	// which is closed again once composer updates successfully
+	FailureIssue bool
+
+	// JobURL is the URL of the CI job, linked in failure issues
+	JobURL string
+
+	// APIURL is the GitLab API URL, eg: https://gitlab.com/api/v4
+	APIURL string
+
+	// Token is the GitLab API token
+	Token string
+
+	// ProjectID is the GitLab project ID
+	ProjectID string
+
+	// ProjectPath is the GitLab project path, eg: group/project
+	ProjectPath string
+
+	// RepositoryURL is the repository clone URL, used to set the authenticated push URL.
+	// If not set, the existing git remote is used as-is.
+	RepositoryURL string
+
+	// Remote is the git remote to push to, and to detect the API URL &
+	// project from if not set
+	Remote string
+
+	// Runner executes all external commands, defaults to ExecRunner
+	Runner Runner
+
+	// Output is where all progress is written to, defaults to os.Stdout
+	Output io.Writer
+
+	// untrustedCommands removes the API token from the environment of the commands
+	// set in the repository configuration file, as in batch mode
+	untrustedCommands bool
+
+	// gitEnv are additional environment variables of the git commands, eg: the
+	// credentials of batch mode, which are never stored in the clone
+	gitEnv []string
+}
+
+// DefaultOptions returns the default options
+func DefaultOptions() Options {
+	return Options{
+		RepoDir:            ".",
+		ConfigFile:         ConfigFile,
+		ComposerVersion:    2,
+		ComposerFlags:      []string{},
+		ComposerRetries:    2,
+		ComposerRetryDelay: 5 * time.Second,
+		PHPIni:             []string{},
+		GitCommitTitle:     "Update composer dependencies",
+		MRTitlePrefix:      "Composer update:",
+		Labels:             []string{},
+		Assignees:          []string{},
+		Reviewers:          []string{},
+		ReplaceOpen:        true,
+		Rebase:             "conflicted",
+		RebaseLabel:        "rebase",
+		Remote:             "origin",
+		FailureIssue:       true,
+		Validate:           []string{},
+		ValidatePolicy:     "abort",
+		Verify:             []string{},
+		PreUpdate:          []string{},
+		PostUpdate:         []string{},
+		CommitPaths:        []string{},
+		UnexpectedFiles:    "warn",
+		SecretPatterns:     []string{},
+		VerifyPolicy:       "draft",
+		MRMode:             "combined",
+		UpdatePolicy:       "full",
+	}
+}
+
+// LoadEnv sets the options from the CI environment variables (if set)
+func (o *Options) LoadEnv() {
+	o.ComposerVersion = envInt("COMPOSER_MR_COMPOSER_VERSION", o.ComposerVersion)
+	o.ComposerTimeout = envDuration("COMPOSER_MR_COMPOSER_TIMEOUT", o.ComposerTimeout)
+	o.ComposerRetries = envCount("COMPOSER_MR_COMPOSER_RETRIES", o.ComposerRetries)
+	o.ComposerMemoryLimit = envString("COMPOSER_MR_COMPOSER_MEMORY_LIMIT", o.ComposerMemoryLimit)
+	o.PHPPath = envString("COMPOSER_MR_PHP", o.PHPPath)
+	o.PlatformPHP = envString("COMPOSER_MR_PLATFORM_PHP", o.PlatformPHP)
+	o.PHPVersions = envCSVSlice("COMPOSER_MR_PHP_VERSIONS", o.PHPVersions)
+	o.Validate = envCSVSlice("COMPOSER_MR_VALIDATE", o.Validate)
+	o.ValidatePolicy = strings.ToLower(envString("COMPOSER_MR_VALIDATE_POLICY", o.ValidatePolicy))
+	o.Verify = envLines("COMPOSER_MR_VERIFY", o.Verify)
+	o.PreUpdate = envLines("COMPOSER_MR_PRE_UPDATE", o.PreUpdate)
+	o.PostUpdate = envLines("COMPOSER_MR_POST_UPDATE", o.PostUpdate)
+	o.CommitPaths = trimSlice(envCSVSlice("COMPOSER_MR_COMMIT_PATHS", o.CommitPaths))
+	o.UnexpectedFiles = strings.ToLower(envString("COMPOSER_MR_UNEXPECTED_FILES", o.UnexpectedFiles))
+	o.SecretPatterns = envLines("COMPOSER_MR_SECRET_PATTERNS", o.SecretPatterns)
+	o.VerifyPolicy = strings.ToLower(envString("COMPOSER_MR_VERIFY_POLICY", o.VerifyPolicy))
+	o.UpdatePolicy = strings.ToLower(envString("COMPOSER_MR_UPDATE_POLICY", o.UpdatePolicy))
+	o.Bisect = envString("COMPOSER_MR_BISECT", o.Bisect)
+	o.MinReleaseAge = envCount("COMPOSER_MR_MIN_RELEASE_AGE", o.MinReleaseAge)
+	o.Schedule = envLines("COMPOSER_MR_SCHEDULE", o.Schedule)
+	o.MaxOpenMRs = envCount("COMPOSER_MR_MAX_OPEN", o.MaxOpenMRs)
+	o.MRMode = strings.ToLower(envString("COMPOSER_MR_MODE", o.MRMode))
+	o.PHPIni = envCSVSlice("COMPOSER_MR_PHP_INI", o.PHPIni)
+	o.BranchPrefix = envString("COMPOSER_MR_BRANCH_PREFIX", o.BranchPrefix)
+	o.BranchName = envString("COMPOSER_MR_BRANCH_NAME", o.BranchName)
+	o.GitCommitTitle = envString("COMPOSER_MR_COMMIT_TITLE", o.GitCommitTitle)
+	o.MRTitlePrefix = envString("COMPOSER_MR_TITLE_PREFIX", o.MRTitlePrefix)
+	o.Labels = envCSVSlice("COMPOSER_MR_LABELS", o.Labels)
+	o.Assignees = envCSVSlice("COMPOSER_MR_ASSIGNEES", o.Assignees)
+	o.Reviewers = envCSVSlice("COMPOSER_MR_REVIEWERS", o.Reviewers)
+	o.RotateReviewers = envTrue("COMPOSER_MR_REVIEWERS_ROTATE", o.RotateReviewers)
+	o.CodeOwners = envTrue("COMPOSER_MR_CODEOWNERS", o.CodeOwners)
+	o.ReplaceOpen = envTrue("COMPOSER_MR_REPLACE_OPEN", o.ReplaceOpen)
+	o.Rebase = strings.ToLower(envString("COMPOSER_MR_REBASE", o.Rebase))
+	o.RebaseLabel = envString("COMPOSER_MR_REBASE_LABEL", o.RebaseLabel)
+	o.Remote = envString("COMPOSER_MR_REMOTE", o.Remote)
+	o.FailureIssue = envTrue("COMPOSER_MR_FAILURE_ISSUE", o.FailureIssue)
+	o.JobURL = envString("CI_JOB_URL", o.JobURL)
+
+	// fallback to original variable
+	o.Token = envString("GITLAB_API_PRIVATE_TOKEN", o.Token)
+	o.Token = envString("COMPOSER_MR_TOKEN", o.Token)
+	o.APIURL = envString("CI_API_V4_URL", o.APIURL)
+	o.ProjectID = envString("CI_PROJECT_ID", o.ProjectID)
+	o.ProjectPath = envString("CI_PROJECT_PATH", o.ProjectPath)
+	o.RepositoryURL = envString("CI_REPOSITORY_URL", o.RepositoryURL)
+	o.APIURL = envString("COMPOSER_MR_API_URL", o.APIURL)
+	if project := envString("COMPOSER_MR_PROJECT", ""); project != "" {
+		o.SetProject(project)
+	}
+}
+
+// SetProject sets the GitLab project by either its numeric ID or path
+func (o *Options) SetProject(project string) {
+	if _, err := strconv.Atoi(project); err == nil {
+		o.ProjectID = project
+		o.ProjectPath = ""
+		return
+	}
+
+	o.ProjectID = ""
+	o.ProjectPath = strings.Trim(project, "/")
+}
+
+// Secrets returns the credentials which must never be recorded: the API token (also
+// as the git credentials of batch mode) & the password of the repository URL (the CI job token)
+func (o *Options) Secrets() []string {
+	secrets := []string{o.Token}
+	if o.Token != "" {
+		secrets = append(secrets, basicAuth(o.Token))
+	}
+
+	if u, err := url.Parse(o.RepositoryURL); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			secrets = append(secrets, password)
+		}
+	}
+
+	return secrets
+}
+
+// ConfigError contains all the configuration errors
+type ConfigError struct {
+	Errors []error
+}
+
+// Error returns a list of all configuration errors
+func (e *ConfigError) Error() string {
+	lines := []string{"invalid configuration:"}
+	for _, err := range e.Errors {
+		lines = append(lines, fmt.Sprintf("- %v", err))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// validate will ensure the correct options are set, locating binaries
+// and setting defaults where required
+func (o *Options) validate() error {
+	errors := []error{}
+	var err error
+
+	if o.GitUser == "" || o.GitEmail == "" {
+		errors = append(errors, fmt.Errorf("git commit user & email not set"))
+	}
+
+	if o.GitBranch == "" && len(o.Branches) > 0 {
+		o.GitBranch = o.Branches[0].Name
+	}
+
+	if o.GitBranch == "" {
+		errors = append(errors, fmt.Errorf("source branch not set"))
+	}
+
+	errors = append(errors, o.validateBranches()...)
+
+	if o.ComposerPath == "" {
+		composerVersion := fmt.Sprintf("composer-%d", o.ComposerVersion)
+
+		o.ComposerPath, err = which(composerVersion)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("\"%s\" not found", composerVersion))
+		}
+	}
+
+	// the slice is shared by all projects in batch mode
+	o.PHPVersions = slices.Clone(o.PHPVersions)
+	for i, v := range o.PHPVersions {
+		o.PHPVersions[i] = strings.TrimSpace(v)
+		if _, _, err := parseVersion(o.PHPVersions[i]); err != nil {
+			errors = append(errors, fmt.Errorf("invalid PHP version \"%s\"", v))
+		}
+	}
+
+	checks := []string{}
+	for _, c := range o.Validate {
+		c = strings.ToLower(strings.TrimSpace(c))
+		switch {
+		case c == "all":
+			checks = append(checks, ValidationChecks...)
+		case slices.Contains(ValidationChecks, c):
+			checks = append(checks, c)
+		case c != "" && c != "none":
+			errors = append(errors, fmt.Errorf("invalid validation check \"%s\"", c))
+		}
+	}
+	o.Validate = checks
+
+	switch o.ValidatePolicy {
+	case "abort", "report":
+	default:
+		errors = append(errors, fmt.Errorf("invalid validation policy \"%s\"", o.ValidatePolicy))
+	}
+
+	switch o.VerifyPolicy {
+	case "draft", "skip":
+	default:
+		errors = append(errors, fmt.Errorf("invalid verify policy \"%s\"", o.VerifyPolicy))
+	}
+
+	switch o.UnexpectedFiles {
+	case "warn", "fail":
+	default:
+		errors = append(errors, fmt.Errorf("invalid unexpected files policy \"%s\"", o.UnexpectedFiles))
+	}
+
+	for _, pattern := range o.SecretPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errors = append(errors, fmt.Errorf("invalid secret pattern \"%s\": %w", pattern, err))
+		}
+	}
+
+	if o.PHPPath == "" && len(o.PHPIni) > 0 {
+		o.PHPPath, err = which("php")
+		if err != nil {
+			errors = append(errors, fmt.Errorf("\"php\" not found"))
+		}
+	}
+
+	if o.GitPath == "" {
+		o.GitPath, err = which("git")
+		if err != nil {
+			errors = append(errors, fmt.Errorf("\"git\" not found"))
+		}
+	}
+
+	if o.RepoDir == "" {
+		o.RepoDir = "."
+	}
+
+	o.RepoDir, err = absPath(o.RepoDir)
+	if err != nil {
+		errors = append(errors, err)
+	}
+
+	if o.Token == "" {
+		errors = append(errors, fmt.Errorf("gitlab API token not set"))
+	}
+
+	if o.Remote == "" {
+		o.Remote = "origin"
+	}
+
+	switch o.Rebase {
+	case "behind", "conflicted", "never":
+	default:
+		errors = append(errors, fmt.Errorf("invalid rebase option \"%s\"", o.Rebase))
+	}
+
+	for _, rule := range o.Schedule {
+		if _, err := parseSchedule(rule); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, g := range o.Groups {
+		if g.Name == "" || names[g.Name] {
+			errors = append(errors, fmt.Errorf("invalid group name \"%s\": groups require a unique name", g.Name))
+		}
+		names[g.Name] = true
+
+		if len(g.Packages) == 0 {
+			errors = append(errors, fmt.Errorf("group %s has no packages", g.Name))
+		}
+
+		for _, rule := range g.Schedule {
+			if _, err := parseSchedule(rule); err != nil {
+				errors = append(errors, fmt.Errorf("group %s: %w", g.Name, err))
+			}
+		}
+	}
+
+	switch o.MRMode {
+	case "combined", "package":
+	default:
+		errors = append(errors, fmt.Errorf("invalid merge request mode \"%s\"", o.MRMode))
+	}
+
+	if err := o.validateBranchTemplate(); err != nil {
+		errors = append(errors, err)
+	}
+
+	if o.MaxOpenMRs < 0 {
+		errors = append(errors, fmt.Errorf("invalid maximum open merge requests \"%d\"", o.MaxOpenMRs))
+	}
+
+	if o.MinReleaseAge < 0 {
+		errors = append(errors, fmt.Errorf("invalid minimum release age \"%d\"", o.MinReleaseAge))
+	}
+
+	if o.Runner == nil {
+		o.Runner = ExecRunner{}
+	}
+
+	if o.Output == nil {
+		o.Output = os.Stdout
+	}
+
+	if len(errors) > 0 {
+		return &ConfigError{Errors: errors}
+	}
+
+	return nil
+}
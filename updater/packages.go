@@ -0,0 +1,246 @@
+package updater
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// PackageResult is the result of a single package merge request in package mode
+type PackageResult struct {
+	// Package is the package name
+	Package string
+	// Status is the outcome of the package update
+	Status Status
+	// Checksum is the checksum of the composer.lock with only the package updated
+	Checksum string
+	// MergeRequest is the created, regenerated or existing merge request
+	MergeRequest *gitlab.MergeRequest
+	// Verification are the results of the verify commands
+	Verification []CheckResult
+	// Hooks are the results of the pre- & post-update hooks
+	Hooks []CheckResult
+	// Err is the error updating the package, if any
+	Err error
+}
+
+// RunPackages creates (or updates) a merge request per updated package, each
+// updating only that package (with its dependencies) from the original lock file.
+// Packages are processed in order of priority for the open merge request limits.
+func (u *Updater) runPackages(original []byte, pre, post ComposerLock, advisories map[string][]Advisory, result *Result) error {
+	full := u.compareDiffs(pre, post)
+	result.Checksum = full.Checksum
+	result.Packages = full.Packages
+
+	packages := []ComposerDiffPackage{}
+	for _, p := range full.Packages {
+		// added & removed packages are dependencies, and cannot be updated on their own
+		if p.PreVersion != "" && p.PostVersion != "" {
+			packages = append(packages, p)
+		}
+	}
+
+	sort.SliceStable(packages, func(i, j int) bool {
+		ti, tj := updateTypeOf(packages[i], advisories), updateTypeOf(packages[j], advisories)
+		if ti != tj {
+			return ti < tj
+		}
+		return packages[i].Name < packages[j].Name
+	})
+
+	botMRs, err := u.listBotMRs(u.opts.GitBranch)
+	if err != nil {
+		return fmt.Errorf("listing merge requests: %w", err)
+	}
+
+	var limiter *mrLimiter
+	if u.limitsEnabled() {
+		// existing package merge requests are updated, so none are replaced
+		if limiter, err = u.newMRLimiter(nil); err != nil {
+			return fmt.Errorf("listing open merge requests: %w", err)
+		}
+	}
+
+	failed := []string{}
+
+	for _, p := range packages {
+		pr := u.updatePackage(original, pre, p, botMRs, limiter, advisories, result)
+		if pr.Err != nil {
+			u.printf("Error updating %s: %s\n", p.Name, pr.Err)
+			failed = append(failed, p.Name)
+		}
+
+		result.PackageResults = append(result.PackageResults, pr)
+	}
+
+	if err := u.resetBranch(); err != nil {
+		return fmt.Errorf("switching branch: %w", err)
+	}
+
+	if len(result.Pending) > 0 && u.allotment == nil {
+		u.printPending(result.Pending)
+	}
+
+	statuses := []Status{}
+	for _, pr := range result.PackageResults {
+		statuses = append(statuses, pr.Status)
+	}
+	result.Status = combinedStatus(statuses)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("updating %s failed", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// UpdatePackage updates a single package from the original lock file, and creates
+// (or updates) its merge request
+func (u *Updater) updatePackage(original []byte, pre ComposerLock, p ComposerDiffPackage, botMRs []botMR, limiter *mrLimiter, advisories map[string][]Advisory, result *Result) PackageResult {
+	pr := PackageResult{Package: p.Name, Status: StatusNoUpdates}
+
+	u.printf("\n==========\nUpdating %s\n==========\n", p.Name)
+
+	if err := u.resetBranch(); err != nil {
+		pr.Err = fmt.Errorf("switching branch: %w", err)
+		return pr
+	}
+
+	// the changes of the pre-update hooks are discarded with the branch reset
+	var err error
+	if pr.Hooks, err = u.runHooks("pre-update", u.opts.PreUpdate); err != nil {
+		pr.Err = err
+		return pr
+	}
+
+	if err := u.partialUpdate(original, []string{"--with-dependencies", p.Name}); err != nil {
+		pr.Err = err
+		return pr
+	}
+
+	post, err := u.parseComposerLock()
+	if err != nil {
+		pr.Err = fmt.Errorf("parsing composer.lock: %w", err)
+		return pr
+	}
+
+	if post.Checksum == pre.Checksum {
+		u.printf("%s cannot be updated on its own\n", p.Name)
+		return pr
+	}
+
+	diff, err := u.describeUpdate(pre, post, &Result{})
+	if err != nil {
+		pr.Err = err
+		return pr
+	}
+	pr.Checksum = diff.Checksum
+
+	u.mrPackage = p.Name
+	u.mrGroup = ""
+	if g := u.packageGroup(p.Name); g != nil {
+		u.mrGroup = g.Name
+	}
+	u.mrBranch = u.branchName(diff.Checksum, p.Name)
+
+	title := fmt.Sprintf("%s %s %s", u.opts.MRTitlePrefix, p.Name, p.PostVersion)
+
+	var existing *gitlab.MergeRequest
+	reason := ""
+
+	for _, mr := range botMRs {
+		if mr.Metadata.Package != p.Name {
+			continue
+		}
+
+		existing = mr.MergeRequest
+		pr.MergeRequest = existing
+
+		if mr.Metadata.Checksum != diff.Checksum {
+			reason = fmt.Sprintf("a newer update of %s is available", p.Name)
+		} else if reason = u.regenerateReason(existing); reason == "" {
+			u.printf("An identical merge request already exists for %s: !%d\n", p.Name, existing.IID)
+			pr.Status = StatusExists
+			return pr
+		}
+	}
+
+	if existing == nil && limiter != nil {
+		pending := PendingMR{
+			Title:    title,
+			Group:    u.mrGroup,
+			Priority: updatePriority([]ComposerDiffPackage{p}, advisories),
+			Packages: diff.Packages,
+		}
+
+		allowed, decided := u.allotMR(pending, nil)
+		if !decided {
+			allowed = limiter.allow(u.mrGroup)
+		}
+
+		if !allowed {
+			result.Pending = append(result.Pending, pending)
+			pr.Status = StatusPending
+			return pr
+		}
+	}
+
+	hooks, err := u.runHooks("post-update", u.opts.PostUpdate)
+	pr.Hooks = append(pr.Hooks, hooks...)
+	if err != nil {
+		pr.Err = err
+		return pr
+	}
+	diff.Description += hooksReport(pr.Hooks)
+
+	pr.Verification = u.runVerification()
+	if failed := failedChecks(pr.Verification); len(failed) > 0 {
+		if u.opts.VerifyPolicy == "skip" {
+			pr.Err = fmt.Errorf("verification failed, merge request skipped: %s", strings.Join(failed, ", "))
+			return pr
+		}
+		title = "Draft: " + title
+	}
+	diff.Description += checksReport("Verification", pr.Verification)
+
+	files, err := u.commitFiles(&diff, &Result{})
+	if err != nil {
+		pr.Err = err
+		return pr
+	}
+
+	if existing != nil {
+		u.printf("Regenerating merge request !%d: %s\n", existing.IID, reason)
+
+		if err := u.updateMergeBranch(diff, existing.SourceBranch, files); err != nil {
+			pr.Err = fmt.Errorf("updating merge request branch: %w", err)
+			return pr
+		}
+
+		if err := u.updateMergeRequest(existing, title, diff.Description, diff.Checksum, reason); err != nil {
+			pr.Err = err
+			return pr
+		}
+
+		pr.Status = StatusRegenerated
+		return pr
+	}
+
+	if err := u.updateMergeBranch(diff, u.mrBranch, files); err != nil {
+		pr.Err = fmt.Errorf("creating merge request: %w", err)
+		return pr
+	}
+
+	mr, err := u.createMergeRequest(title, diff.Description, diff.Checksum)
+	if err != nil {
+		pr.Err = err
+		return pr
+	}
+
+	pr.Status = StatusCreated
+	pr.MergeRequest = mr
+
+	return pr
+}
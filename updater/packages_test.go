@@ -0,0 +1,149 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestUpdatePackageMode(t *testing.T) {
+	pre := bisectLock("1.0.0", "1.0.0", "1.0.0")
+	onlyA := bisectLock("1.1.0", "1.0.0", "1.0.0")
+	onlyB := bisectLock("1.0.0", "1.1.0", "1.0.0")
+	onlyC := bisectLock("1.0.0", "1.0.0", "2.0.0")
+
+	update := func(args ...string) []string {
+		return append([]string{"update", "--no-interaction", "--no-progress"}, args...)
+	}
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: update(), Do: writeLock(bisectLock("1.1.0", "1.1.0", "2.0.0"))},
+		{Bin: "composer-2", Args: update("--with-dependencies", "vendor/a"), Do: writeLock(onlyA)},
+		{Bin: "composer-2", Args: update("--with-dependencies", "vendor/b"), Do: writeLock(onlyB)},
+		{Bin: "composer-2", Args: update("--with-dependencies", "vendor/c"), Do: writeLock(onlyC)},
+	}}
+	// the branch is reset before each package & after the last package
+	for i := 0; i < 4; i++ {
+		runner.Responses = append(runner.Responses, FakeResponse{Bin: "git", Args: []string{"checkout", "--force", "main"}})
+	}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/vendor-a", []string{"vendor/a: 1.0.0...1.1.0"})...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/vendor-b", []string{"vendor/b: 1.0.0...1.1.0"})...)
+
+	u, server := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.MRMode = "package"
+
+	outdated := server.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Composer update: vendor/b 1.0.5", SourceBranch: "composer-update/vendor-b", TargetBranch: "main",
+		Description: "## Updated Composer Packages" + mrMarker(MRMetadata{Checksum: "old", TargetBranch: "main", Package: "vendor/b"}),
+	})
+	identical := server.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Composer update: vendor/c 2.0.0", SourceBranch: "composer-update/vendor-c", TargetBranch: "main",
+		Description: "## Updated Composer Packages" + mrMarker(MRMetadata{Checksum: checksum(onlyC), TargetBranch: "main", Package: "vendor/c"}),
+	})
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	expected := map[string]Status{"vendor/a": StatusCreated, "vendor/b": StatusRegenerated, "vendor/c": StatusExists}
+	if result.Status != StatusCreated || len(result.PackageResults) != 3 {
+		t.Fatalf("unexpected result: %v %+v", result.Status, result.PackageResults)
+	}
+	for _, pr := range result.PackageResults {
+		if pr.Status != expected[pr.Package] || pr.Err != nil {
+			t.Errorf("%s: expected %s, got %s (%v)", pr.Package, expected[pr.Package], pr.Status, pr.Err)
+		}
+	}
+
+	if !hasCall(runner.Calls, "git", "checkout", "-B", "composer-update/vendor-a") || !hasCall(runner.Calls, "git", "push", "--force", "origin", "composer-update/vendor-a") {
+		t.Error("expected the stable package branch to be pushed")
+	}
+
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 3 {
+		t.Fatalf("expected 3 open merge requests, got %d", len(mrs))
+	}
+
+	for _, mr := range mrs {
+		meta, _ := u.parseMRMetadata(mr)
+		switch mr.IID {
+		case outdated.IID:
+			if meta.Checksum != checksum(onlyB) {
+				t.Errorf("expected the vendor/b merge request to be updated, got %s", meta.Checksum)
+			}
+		case identical.IID:
+		default:
+			if mr.SourceBranch != "composer-update/vendor-a" || meta.Package != "vendor/a" || !strings.HasSuffix(mr.Title, "vendor/a 1.1.0") {
+				t.Errorf("unexpected merge request: %s %s %+v", mr.Title, mr.SourceBranch, meta)
+			}
+		}
+	}
+}
+
+func TestUpdatePackageModeKeepsHeldBackReleases(t *testing.T) {
+	old := time.Now().AddDate(0, 0, -30).UTC().Format(time.RFC3339)
+	recent := time.Now().AddDate(0, 0, -1).UTC().Format(time.RFC3339)
+
+	lock := func(a, b, bTime string) string {
+		return fmt.Sprintf(`{
+    "content-hash": "abc",
+    "packages": [
+        {"name": "vendor/a", "version": "%s", "require": {"vendor/b": "*"}, "time": "%s"},
+        {"name": "vendor/b", "version": "%s", "time": "%s"}
+    ],
+    "packages-dev": []
+}
+`, a, old, b, bTime)
+	}
+
+	update := func(args ...string) []string {
+		return append([]string{"update", "--no-interaction", "--no-progress"}, args...)
+	}
+
+	pre := lock("1.0.0", "1.0.0", old)
+	onlyA := lock("1.1.0", "1.0.0", old)
+
+	// without the hold vendor/b would be updated as a dependency of vendor/a
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: update(), Do: writeLock(lock("1.1.0", "2.0.0", recent))},
+		{Bin: "composer-2", Args: update("vendor/a", "--with=vendor/b:1.0.0"), Do: writeLock(onlyA)},
+		{Bin: "composer-2", Args: update("--with-dependencies", "vendor/a", "--with=vendor/b:1.0.0"), Do: writeLock(onlyA)},
+		{Bin: "git", Args: []string{"checkout", "--force", "main"}},
+		{Bin: "git", Args: []string{"checkout", "--force", "main"}},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/vendor-a", []string{"vendor/a: 1.0.0...1.1.0"})...)
+
+	u, _ := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.MRMode = "package"
+	u.opts.MinReleaseAge = 7
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if len(result.PackageResults) != 1 || result.PackageResults[0].Checksum != checksum(onlyA) || len(result.HeldBack) != 1 {
+		t.Errorf("expected vendor/a to be updated without vendor/b, got %+v %+v", result.PackageResults, result.HeldBack)
+	}
+}
@@ -0,0 +1,131 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PHPIncompatibility is a package (or composer.json) which cannot be
+// installed on one or more PHP versions
+type PHPIncompatibility struct {
+	// Package is the package name, or "composer.json" for the root requirement
+	Package string
+	// Constraint is the required PHP version constraint
+	Constraint string
+	// Versions are the incompatible PHP versions
+	Versions []string
+}
+
+// SetPlatform sets config.platform.php in composer.json for the update,
+// returning a function to restore the original composer.json
+func (u *Updater) setPlatform() (func(updated bool) error, error) {
+	composerJSON := filepath.Join(u.opts.RepoDir, "composer.json")
+
+	original, err := os.ReadFile(filepath.Clean(composerJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	u.printf("Setting the target platform to PHP %s\n", u.opts.PlatformPHP)
+
+	if _, err := u.composer("config", "--no-interaction", "platform.php", u.opts.PlatformPHP); err != nil {
+		return nil, err
+	}
+
+	return func(updated bool) error {
+		if err := os.WriteFile(composerJSON, original, 0600); err != nil {
+			return err
+		}
+
+		if !updated {
+			return nil
+		}
+
+		// the lock content-hash & platform overrides include config.platform
+		args := []string{"update", "--lock", "--no-interaction", "--no-progress", "--no-scripts"}
+		if u.opts.ComposerVersion == 1 {
+			args = append(args, "--ignore-platform-reqs")
+		} else {
+			args = append(args, "--no-install", "--ignore-platform-req=php")
+		}
+
+		if _, err := u.composer(args...); err != nil {
+			return fmt.Errorf("updating the lock file hash: %w", err)
+		}
+
+		return nil
+	}, nil
+}
+
+// PHPIncompatibilities returns all packages of the lock file (and the composer.json
+// requirement) which are incompatible with any of the PHP versions
+func phpIncompatibilities(lock ComposerLock, versions []string) []PHPIncompatibility {
+	results := []PHPIncompatibility{}
+
+	check := func(name, constraint string) {
+		if constraint == "" {
+			return
+		}
+
+		incompatible := []string{}
+		for _, v := range versions {
+			ok, err := constraintMatches(constraint, v)
+			if err == nil && !ok {
+				incompatible = append(incompatible, v)
+			}
+		}
+
+		if len(incompatible) > 0 {
+			results = append(results, PHPIncompatibility{Package: name, Constraint: constraint, Versions: incompatible})
+		}
+	}
+
+	check("composer.json", lock.Platform["php"])
+
+	packages := append(append([]Package{}, lock.Packages...), lock.PackagesDev...)
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+
+	for _, p := range packages {
+		check(p.Name, p.Require["php"])
+	}
+
+	return results
+}
+
+// PHPCompatibilityReport returns the markdown PHP version compatibility
+// report for the merge request description
+func (u *Updater) phpCompatibilityReport(lock ComposerLock) string {
+	if len(u.opts.PHPVersions) == 0 {
+		return ""
+	}
+
+	report := "\n\n### PHP compatibility\n\n"
+
+	incompatible := phpIncompatibilities(lock, u.opts.PHPVersions)
+	if len(incompatible) == 0 {
+		return report + fmt.Sprintf("All packages are compatible with PHP %s.", strings.Join(u.opts.PHPVersions, ", "))
+	}
+
+	report += "The following packages are **not** compatible with all the PHP versions:\n\n"
+	report += "| Package | Requires PHP | " + strings.Join(u.opts.PHPVersions, " | ") + " |\n"
+	report += "|---|---|" + strings.Repeat("---|", len(u.opts.PHPVersions)) + "\n"
+
+	for _, p := range incompatible {
+		row := fmt.Sprintf("| %s | `%s` |", p.Package, strings.ReplaceAll(p.Constraint, "|", "\\|"))
+		for _, v := range u.opts.PHPVersions {
+			mark := " ✅ |"
+			for _, iv := range p.Versions {
+				if iv == v {
+					mark = " ❌ |"
+				}
+			}
+			row += mark
+		}
+		report += row + "\n"
+	}
+
+	return strings.TrimSuffix(report, "\n")
+}
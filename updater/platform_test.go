@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConstraintMatches(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"^8.1", "8.1", true},
+		{"^8.1", "8.3", true},
+		{"^8.1", "9.0", false},
+		{"^8.2", "8.1", false},
+		{"^0.3", "0.4", false},
+		{"~8.1.0", "8.1.27", true},
+		{"~8.1.0", "8.2", false},
+		{"~8.1", "8.4", true},
+		{">=7.4 <8.2", "8.1", true},
+		{">=7.4,<8.2", "8.2", false},
+		{">= 8.1", "8.2", true},
+		{"^7.4 || ^8.0", "8.3", true},
+		{"^7.2|~8.0.0", "8.1", false},
+		{"8.0 - 8.2", "8.2.10", true},
+		{"8.0 - 8.2", "8.3", false},
+		{"8.*", "8.3", true},
+		{"7.*", "8.0", false},
+		{"*", "8.3", true},
+		{">=8.1.0-dev", "8.1", true},
+		{"!=8.1.*", "8.1.5", false},
+		// partial versions are compared as their highest patch release
+		{"^8.0.2", "8.0", true},
+		{"~8.0.2", "8.0", true},
+		{">8.1.10 <8.2", "8.1", true},
+		{"~8.0.2", "8.0.1", false},
+		{">8.1.5", "8.1.5", false},
+	}
+
+	for _, tt := range tests {
+		ok, err := constraintMatches(tt.constraint, tt.version)
+		if err != nil {
+			t.Errorf("%s: %v", tt.constraint, err)
+			continue
+		}
+		if ok != tt.expected {
+			t.Errorf("expected %q matching %s to be %v", tt.constraint, tt.version, tt.expected)
+		}
+	}
+}
+
+func TestUpdatePlatform(t *testing.T) {
+	const composerJSON = `{"require": {"vendor/pkg": "^1.0"}}`
+
+	lock := strings.Replace(postLock, `"version": "1.1.0",`, `"version": "1.1.0", "require": {"php": ">=8.2"},`, 1)
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"config", "--no-interaction", "platform.php", "8.1"}, Do: func(c Command) {
+			_ = os.WriteFile(filepath.Join(c.Dir, "composer.json"), []byte(`{"config": {"platform": {"php": "8.1"}}}`), 0600)
+		}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(lock)},
+		{Bin: "composer-2", Args: []string{"update", "--lock", "--no-interaction", "--no-progress", "--no-scripts", "--no-install", "--ignore-platform-req=php"}},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/pkg: 1.0.0...1.1.0"})...)
+	u, server := setup(t, runner, "main")
+	u.opts.PlatformPHP = "8.1"
+	u.opts.PHPVersions = []string{"8.1", "8.2"}
+
+	if err := os.WriteFile(filepath.Join(u.opts.RepoDir, "composer.json"), []byte(composerJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := u.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(u.opts.RepoDir, "composer.json")); string(b) != composerJSON {
+		t.Errorf("expected composer.json to be restored, got %s", b)
+	}
+
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 1 || !strings.Contains(mrs[0].Description, "| vendor/pkg | `>=8.2` | ❌ | ✅ |") {
+		t.Errorf("expected PHP compatibility report in description: %v", mrs)
+	}
+}
@@ -0,0 +1,248 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// FakeResponse is a scripted response of the FakeRunner
+type FakeResponse struct {
+	// Bin is the base name of the binary, eg "git"
+	Bin string
+	// Args are the expected arguments, nil matches any arguments
+	Args []string
+	// Output is the returned Stdout & Stderr
+	Output string
+	// Err is the returned error
+	Err error
+	// Do is an optional side effect, eg: modifying composer.lock
+	Do func(c Command)
+}
+
+// FakeRunner returns scripted responses instead of executing commands.
+// Every response is used once, in the order they match.
+type FakeRunner struct {
+	Responses []FakeResponse
+	// Calls contains all executed commands
+	Calls []Command
+
+	mu   sync.Mutex
+	used map[int]bool
+}
+
+// Run returns the first unused matching response
+func (f *FakeRunner) Run(_ context.Context, c Command) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.used == nil {
+		f.used = make(map[int]bool)
+	}
+
+	f.Calls = append(f.Calls, c)
+
+	for i, r := range f.Responses {
+		if f.used[i] || r.Bin != filepath.Base(c.Bin) {
+			continue
+		}
+		if r.Args != nil && !reflect.DeepEqual(r.Args, c.Args) {
+			continue
+		}
+
+		f.used[i] = true
+
+		if r.Do != nil {
+			r.Do(c)
+		}
+
+		return r.Output, r.Err
+	}
+
+	return "", fmt.Errorf("unexpected command: %s %s", filepath.Base(c.Bin), strings.Join(c.Args, " "))
+}
+
+// Unused returns all responses which were not used
+func (f *FakeRunner) Unused() []FakeResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	unused := []FakeResponse{}
+	for i, r := range f.Responses {
+		if !f.used[i] {
+			unused = append(unused, r)
+		}
+	}
+
+	return unused
+}
+
+// Fixture is a single recorded command
+type Fixture struct {
+	Bin    string   `json:"bin"`
+	Args   []string `json:"args"`
+	Output string   `json:"output"`
+	Error  string   `json:"error,omitempty"`
+	// Files contains the contents of watched files modified by the command
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// RecordingRunner executes commands with the wrapped Runner, recording all
+// invocations to a fixture file which can be replayed with the ReplayRunner
+type RecordingRunner struct {
+	Runner Runner
+	// File is the fixture file path
+	File string
+	// Files are paths (relative to the command directory) which are
+	// recorded when modified by a command, eg: composer.lock
+	Files []string
+	// Secrets are masked in the recorded fixtures, eg: the API token
+	Secrets []string
+
+	// mu guards the fixtures, as batch mode runs commands concurrently
+	mu       sync.Mutex
+	fixtures []Fixture
+}
+
+// Run executes & records the command
+func (r *RecordingRunner) Run(ctx context.Context, c Command) (string, error) {
+	before := make(map[string]string)
+	for _, f := range r.Files {
+		before[f] = readFileString(filepath.Join(c.Dir, f))
+	}
+
+	out, err := r.Runner.Run(ctx, c)
+
+	fixture := Fixture{
+		Bin:    filepath.Base(c.Bin),
+		Args:   maskSlice(c.Args, r.Secrets),
+		Output: mask(out, r.Secrets),
+	}
+	if err != nil {
+		fixture.Error = mask(err.Error(), r.Secrets)
+	}
+
+	for _, f := range r.Files {
+		if after := readFileString(filepath.Join(c.Dir, f)); after != before[f] {
+			if fixture.Files == nil {
+				fixture.Files = make(map[string]string)
+			}
+			fixture.Files[f] = after
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fixtures = append(r.fixtures, fixture)
+
+	// written after every command so a failing run is still recorded
+	b, jsonErr := json.MarshalIndent(r.fixtures, "", "  ")
+	if jsonErr == nil {
+		jsonErr = os.WriteFile(r.File, b, 0600)
+	}
+	if jsonErr != nil {
+		fmt.Println("Error writing fixtures: ", jsonErr)
+	}
+
+	return out, err
+}
+
+// ReplayRunner replays commands from a fixture file recorded with the RecordingRunner.
+// Commands must be executed in the same order as they were recorded.
+type ReplayRunner struct {
+	// Secrets are masked before comparing the commands, as they were when recorded
+	Secrets []string
+
+	fixtures []Fixture
+	next     int
+}
+
+// NewReplayRunner loads a fixture file
+func NewReplayRunner(file string) (*ReplayRunner, error) {
+	b, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ReplayRunner{}
+	if err := json.Unmarshal(b, &r.fixtures); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", file, err)
+	}
+
+	return r, nil
+}
+
+// Run returns the next recorded output, writing any recorded files
+func (r *ReplayRunner) Run(_ context.Context, c Command) (string, error) {
+	cmd := strings.TrimSpace(filepath.Base(c.Bin) + " " + strings.Join(maskSlice(c.Args, r.Secrets), " "))
+
+	if r.next >= len(r.fixtures) {
+		return "", fmt.Errorf("replay: no recorded command for: %s", cmd)
+	}
+
+	fixture := r.fixtures[r.next]
+	r.next++
+
+	if fixture.Bin != filepath.Base(c.Bin) || !reflect.DeepEqual(fixture.Args, maskSlice(c.Args, r.Secrets)) {
+		return "", fmt.Errorf("replay: expected \"%s %s\", got \"%s\"", fixture.Bin, strings.Join(fixture.Args, " "), cmd)
+	}
+
+	for f, content := range fixture.Files {
+		if err := os.WriteFile(filepath.Join(c.Dir, f), []byte(content), 0600); err != nil {
+			return "", err
+		}
+	}
+
+	if !c.Quiet {
+		var output io.Writer = os.Stdout
+		if c.Output != nil {
+			output = c.Output
+		}
+		fmt.Fprint(output, fixture.Output)
+	}
+
+	if fixture.Error != "" {
+		return fixture.Output, errors.New(fixture.Error)
+	}
+
+	return fixture.Output, nil
+}
+
+// Mask hides secrets from recorded fixtures
+func mask(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[MASKED]")
+		}
+	}
+
+	return s
+}
+
+// MaskSlice masks each string in a slice
+func maskSlice(s []string, secrets []string) []string {
+	masked := []string{}
+	for _, v := range s {
+		masked = append(masked, mask(v, secrets))
+	}
+
+	return masked
+}
+
+// ReadFileString returns the contents of a file, or an empty string if it doesn't exist
+func readFileString(file string) string {
+	b, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
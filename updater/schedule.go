@@ -0,0 +1,217 @@
+package updater
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // schedule time zones, as CI images may not include tzdata
+)
+
+// schedule is a parsed schedule rule, eg: "weekdays before 10:00 Europe/Berlin"
+// or "monthly on the first monday"
+type schedule struct {
+	// days are the allowed weekdays, all days if none are set
+	days    [7]bool
+	hasDays bool
+	// firstWeek limits the days to the first week of the month, eg: "first monday"
+	firstWeek bool
+	// monthDay is the allowed day of the month, 0 for any day
+	monthDay int
+	// after & before are the allowed minutes since midnight, -1 if not set
+	after, before int
+	location      *time.Location
+}
+
+// scheduleFillers are the words ignored in schedule rules
+var scheduleFillers = map[string]bool{
+	"on": true, "the": true, "of": true, "month": true, "every": true, "and": true,
+	"at": true, "in": true, "day": true, "days": true, "daily": true, "any": true, "time": true,
+}
+
+// parseWeekday returns the weekday of a (plural or abbreviated) day name
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.TrimSuffix(s, "s")
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
+
+// parseClock returns the minutes since midnight of a time, eg: "10:00", "9" or "4pm"
+func parseClock(s string) (int, error) {
+	suffix := ""
+	if strings.HasSuffix(s, "am") || strings.HasSuffix(s, "pm") {
+		suffix = s[len(s)-2:]
+	}
+
+	hours, minutes, _ := strings.Cut(strings.TrimSuffix(s, suffix), ":")
+
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 24 || (suffix != "" && (h < 1 || h > 12)) {
+		return 0, fmt.Errorf("invalid time \"%s\"", s)
+	}
+
+	m := 0
+	if minutes != "" {
+		if m, err = strconv.Atoi(minutes); err != nil || m < 0 || m > 59 {
+			return 0, fmt.Errorf("invalid time \"%s\"", s)
+		}
+	}
+
+	// 24:00 is the end of the day
+	if h == 24 && m != 0 {
+		return 0, fmt.Errorf("invalid time \"%s\"", s)
+	}
+
+	// 12am is midnight, 12pm is noon
+	if suffix != "" && h == 12 {
+		h = 0
+	}
+	if suffix == "pm" {
+		h += 12
+	}
+
+	return h*60 + m, nil
+}
+
+// parseSchedule parses a schedule rule. Rules combine (in any order) days
+// ("weekdays", "weekends", "monday", "on mon and fri"), the day of the month
+// ("monthly", "first monday", "first day of the month"), a time window
+// ("after 22:00", "before 6am") and an IANA time zone (default UTC).
+func parseSchedule(rule string) (schedule, error) {
+	s := schedule{after: -1, before: -1, location: time.UTC}
+
+	original := strings.Fields(strings.ReplaceAll(rule, ",", " "))
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(rule, ",", " ")))
+
+	setDay := func(d time.Weekday) {
+		s.days[d] = true
+		s.hasDays = true
+	}
+
+	monthly, weekly := false, false
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+
+		switch {
+		case scheduleFillers[w]:
+		case w == "weekday" || w == "weekdays":
+			for d := time.Monday; d <= time.Friday; d++ {
+				setDay(d)
+			}
+		case w == "weekend" || w == "weekends":
+			setDay(time.Saturday)
+			setDay(time.Sunday)
+		case w == "weekly":
+			weekly = true
+		case w == "monthly":
+			monthly = true
+		case w == "first":
+			if i+1 >= len(words) {
+				return s, fmt.Errorf("invalid schedule \"%s\": missing day after \"first\"", rule)
+			}
+			i++
+			if words[i] == "day" {
+				s.monthDay = 1
+			} else if d, ok := parseWeekday(words[i]); ok {
+				setDay(d)
+				s.firstWeek = true
+			} else {
+				return s, fmt.Errorf("invalid schedule \"%s\": unknown day \"%s\"", rule, words[i])
+			}
+		case w == "before" || w == "after":
+			if i+1 >= len(words) {
+				return s, fmt.Errorf("invalid schedule \"%s\": missing time after \"%s\"", rule, w)
+			}
+			i++
+			m, err := parseClock(words[i])
+			if err != nil {
+				return s, fmt.Errorf("invalid schedule \"%s\": %w", rule, err)
+			}
+			if w == "before" {
+				s.before = m
+			} else {
+				s.after = m
+			}
+		case strings.Contains(w, "/") || w == "utc":
+			loc, err := time.LoadLocation(original[i])
+			if err != nil {
+				return s, fmt.Errorf("invalid schedule \"%s\": %w", rule, err)
+			}
+			s.location = loc
+		default:
+			d, ok := parseWeekday(w)
+			if !ok {
+				return s, fmt.Errorf("invalid schedule \"%s\": unknown word \"%s\"", rule, original[i])
+			}
+			setDay(d)
+		}
+	}
+
+	if monthly && !s.firstWeek && s.monthDay == 0 {
+		s.monthDay = 1
+	}
+
+	if weekly && !s.hasDays {
+		setDay(time.Monday)
+	}
+
+	return s, nil
+}
+
+// matches returns whether the time is within the schedule
+func (s schedule) matches(t time.Time) bool {
+	t = t.In(s.location)
+
+	if s.hasDays && !s.days[t.Weekday()] {
+		return false
+	}
+
+	if s.firstWeek && t.Day() > 7 {
+		return false
+	}
+
+	if s.monthDay > 0 && t.Day() != s.monthDay {
+		return false
+	}
+
+	m := t.Hour()*60 + t.Minute()
+
+	// a window ending before it starts wraps past midnight, eg: "after 22:00 before 6am"
+	if s.after >= 0 && s.before >= 0 && s.after > s.before {
+		return m >= s.after || m < s.before
+	}
+
+	if s.after >= 0 && m < s.after {
+		return false
+	}
+
+	return s.before < 0 || m < s.before
+}
+
+// scheduleMatches returns whether the time is within any of the schedule
+// rules. No rules match any time.
+func scheduleMatches(rules []string, t time.Time) (bool, error) {
+	if len(rules) == 0 {
+		return true, nil
+	}
+
+	for _, rule := range rules {
+		s, err := parseSchedule(rule)
+		if err != nil {
+			return false, err
+		}
+
+		if s.matches(t) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,180 @@
+package updater
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleMatches(t *testing.T) {
+	// Monday 2024-06-03 is the first Monday of the month
+	at := func(s string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04 MST", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		rule string
+		time string
+		want bool
+	}{
+		{"weekdays before 10:00 Europe/Berlin", "2024-06-03 07:30 UTC", true},
+		{"weekdays before 10:00 Europe/Berlin", "2024-06-03 08:30 UTC", false},
+		{"weekdays before 10:00 Europe/Berlin", "2024-06-01 07:30 UTC", false},
+		{"weekends", "2024-06-02 12:00 UTC", true},
+		{"on mon and fri after 10pm", "2024-06-07 22:15 UTC", true},
+		{"on mon and fri after 10pm", "2024-06-06 22:15 UTC", false},
+		{"monthly on the first Monday", "2024-06-03 12:00 UTC", true},
+		{"monthly on the first Monday", "2024-06-10 12:00 UTC", false},
+		{"monthly", "2024-06-01 00:00 UTC", true},
+		{"monthly", "2024-06-02 00:00 UTC", false},
+		{"weekly", "2024-06-03 00:00 UTC", true},
+		{"every day after 9am before 5pm", "2024-06-04 16:59 UTC", true},
+		{"every day after 9am before 5pm", "2024-06-04 17:00 UTC", false},
+		{"after 22:00 before 6am", "2024-06-04 23:30 UTC", true},
+		{"after 22:00 before 6am", "2024-06-04 05:59 UTC", true},
+		{"after 22:00 before 6am", "2024-06-04 06:00 UTC", false},
+		{"after 22:00 before 6am", "2024-06-04 21:59 UTC", false},
+		{"before 24:00", "2024-06-04 23:59 UTC", true},
+	}
+
+	for _, test := range tests {
+		got, err := scheduleMatches([]string{test.rule}, at(test.time))
+		if err != nil {
+			t.Errorf("%s: %v", test.rule, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s at %s: expected %v, got %v", test.rule, test.time, test.want, got)
+		}
+	}
+
+	for _, invalid := range []string{"fortnightly", "before noon", "first week", "weekdays Mars/Olympus", "before 24:59", "after 25:00", "before 24pm"} {
+		if _, err := parseSchedule(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestUpdateSkipsGroups(t *testing.T) {
+	pre := bisectLock("1.0.0", "1.0.0", "1.0.0")
+	full := bisectLock("1.1.0", "1.1.0", "2.0.0")
+	partial := bisectLock("1.1.0", "1.0.0", "1.0.0")
+	tomorrow := strings.ToLower(time.Now().UTC().AddDate(0, 0, 1).Weekday().String())
+
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(full)},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress", "vendor/a", "--with=vendor/b:1.0.0", "--with=vendor/c:1.0.0"}, Do: writeLock(partial)},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/a: 1.0.0...1.1.0"})...)
+
+	u, server := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.Groups = []Group{
+		{Name: "tools", Packages: []string{"vendor/a"}},
+		{Name: "framework", Packages: []string{"vendor/b", "vendor/c"}, Schedule: []string{"on " + tomorrow}},
+	}
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusCreated || result.Checksum != checksum(partial) {
+		t.Errorf("expected a merge request without the framework group, got %v %s", result.Status, result.Checksum)
+	}
+
+	if len(result.SkippedGroups) != 1 || result.SkippedGroups[0].Name != "framework" || len(result.SkippedGroups[0].Packages) != 2 {
+		t.Fatalf("expected the framework group to be skipped, got %+v", result.SkippedGroups)
+	}
+
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 1 || !strings.Contains(mrs[0].Description, "### Skipped groups") || !strings.Contains(mrs[0].Description, "**framework** (`on "+tomorrow+"`)") {
+		t.Fatalf("expected the merge request to note the skipped group, got %v", mrs)
+	}
+
+	// outside the global schedule nothing runs
+	runner = &FakeRunner{}
+	u, _ = setup(t, runner, "main")
+	u.opts.Schedule = []string{"on " + tomorrow}
+
+	if result, err = u.Run(context.Background()); err != nil || result.Status != StatusOutsideSchedule || len(runner.Calls) != 0 {
+		t.Errorf("expected the run to be skipped, got %v %v %v", result.Status, err, runner.Calls)
+	}
+}
+
+func TestUpdateSkipsGroupsKeepsProposedVersions(t *testing.T) {
+	pre := bisectLock("1.0.0", "1.0.0", "1.0.0")
+	full := bisectLock("1.1.0", "1.1.0", "2.0.0")
+	today := strings.ToLower(time.Now().UTC().Weekday().String())
+	tomorrow := strings.ToLower(time.Now().UTC().AddDate(0, 0, 1).Weekday().String())
+
+	// inside the schedule of the group all the packages are updated
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(full)},
+	}}
+	runner.Responses = append(runner.Responses, gitSetupResponses()...)
+	runner.Responses = append(runner.Responses, commitResponses("composer-update/main", []string{"vendor/a: 1.0.0...1.1.0", "vendor/b: 1.0.0...1.1.0", "vendor/c: 1.0.0...2.0.0"})...)
+
+	u, server := setup(t, runner, "main")
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+	u.opts.Groups = []Group{{Name: "framework", Packages: []string{"vendor/b", "vendor/c"}, Schedule: []string{"on " + today}}}
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != StatusCreated || result.Checksum != checksum(full) || len(result.SkippedGroups) != 0 {
+		t.Fatalf("expected a merge request with all the updates, got %v %s %+v", result.Status, result.Checksum, result.SkippedGroups)
+	}
+
+	// outside the schedule the open merge request keeps the proposed versions
+	runner = &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"checkout", "main"}},
+		{Bin: "git", Args: []string{"pull", "--rebase"}},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(full)},
+		{Bin: "git", Args: []string{"fetch", "origin", "+refs/heads/composer-update/main:refs/remotes/origin/composer-update/main"}},
+		{Bin: "git", Args: []string{"show", "origin/composer-update/main:./composer.lock"}, Output: full},
+		{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress", "vendor/a", "vendor/b:1.1.0", "vendor/c:2.0.0"}, Do: writeLock(full)},
+	}}
+
+	opts := u.opts
+	opts.Runner = runner
+	opts.Groups = []Group{{Name: "framework", Packages: []string{"vendor/b", "vendor/c"}, Schedule: []string{"on " + tomorrow}}}
+	u = New(opts)
+	writeLock(pre)(Command{Dir: u.opts.RepoDir})
+
+	if result, err = u.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusExists || result.Checksum != checksum(full) {
+		t.Errorf("expected the merge request to be kept, got %v %s", result.Status, result.Checksum)
+	}
+
+	if len(result.SkippedGroups) != 1 || len(result.SkippedGroups[0].Packages) != 0 || len(result.SkippedGroups[0].Kept) != 2 {
+		t.Fatalf("expected the framework group to keep the proposed versions, got %+v", result.SkippedGroups)
+	}
+
+	if mrs := server.OpenMergeRequests(); len(mrs) != 1 {
+		t.Fatalf("expected the merge request to stay open, got %v", mrs)
+	}
+}
@@ -0,0 +1,168 @@
+package updater
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// secretRule is a pattern of a secret which must never be pushed
+type secretRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// SecretRules are the built-in secret patterns of the staged changes
+var secretRules = []secretRule{
+	{"composer auth", regexp.MustCompile(`"(http-basic|github-oauth|gitlab-oauth|gitlab-token|bitbucket-oauth|bearer)"\s*:`)},
+	{"GitLab token", regexp.MustCompile(`\bgl(pat|dt|rt|ptt|cbt|ft|soat)-[0-9A-Za-z_\-]{20,}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)},
+}
+
+// hunkRe matches a unified diff hunk header, capturing the old line count, and the
+// first new line number & line count
+var hunkRe = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// SecretFinding is a possible secret found in the staged changes
+type SecretFinding struct {
+	// File is the staged file
+	File string
+	// Line is the line number in the file, 0 for the file itself
+	Line int
+	// Rule is the name of the matching rule
+	Rule string
+	// Masked is the line with the secret masked
+	Masked string
+}
+
+// String returns the location & rule of the finding
+func (f SecretFinding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s (%s)", f.File, f.Rule)
+	}
+
+	return fmt.Sprintf("%s:%d (%s)", f.File, f.Line, f.Rule)
+}
+
+// SecretRules returns the built-in & configured secret rules, including the API token
+func (u *Updater) secretRules() []secretRule {
+	rules := append([]secretRule{}, secretRules...)
+
+	for _, pattern := range u.opts.SecretPatterns {
+		// patterns are validated with the options
+		if re, err := regexp.Compile(pattern); err == nil {
+			rules = append(rules, secretRule{pattern, re})
+		}
+	}
+
+	if u.opts.Token != "" {
+		rules = append(rules, secretRule{"API token", regexp.MustCompile(regexp.QuoteMeta(u.opts.Token))})
+	}
+
+	return rules
+}
+
+// ScanSecrets returns the possible secrets in the added lines of a unified diff
+func scanSecrets(diff string, rules []secretRule) []SecretFinding {
+	findings := []SecretFinding{}
+	file := ""
+	line := 0
+	// the remaining old & new lines of the current hunk, headers are outside hunks
+	oldLines, newLines := 0, 0
+
+	for _, l := range strings.Split(diff, "\n") {
+		if oldLines <= 0 && newLines <= 0 {
+			switch {
+			case strings.HasPrefix(l, "+++ "):
+				file = diffPath(strings.TrimPrefix(l, "+++ "))
+				if path.Base(file) == "auth.json" {
+					findings = append(findings, SecretFinding{File: file, Rule: "composer auth file"})
+				}
+			case strings.HasPrefix(l, "@@"):
+				if m := hunkRe.FindStringSubmatch(l); m != nil {
+					oldLines, newLines = hunkCount(m[1]), hunkCount(m[3])
+					line, _ = strconv.Atoi(m[2])
+				}
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(l, "+"):
+			for _, r := range rules {
+				if matches := r.re.FindAllString(l[1:], -1); len(matches) > 0 {
+					findings = append(findings, SecretFinding{File: file, Line: line, Rule: r.name, Masked: mask(l[1:], matches)})
+				}
+			}
+			line++
+			newLines--
+		case strings.HasPrefix(l, "-"):
+			oldLines--
+		case strings.HasPrefix(l, " "):
+			line++
+			oldLines--
+			newLines--
+		}
+	}
+
+	return findings
+}
+
+// HunkCount returns the line count of a hunk header, which defaults to 1
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+
+	n, _ := strconv.Atoi(s)
+
+	return n
+}
+
+// DiffPath returns the path of a diff file header, eg: "b/composer.json". Paths
+// with special characters are quoted, and deleted files are "/dev/null".
+func diffPath(s string) string {
+	s = strings.TrimSuffix(s, "\t")
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			s = unquoted
+		}
+	}
+
+	if s == "/dev/null" {
+		return ""
+	}
+
+	return strings.TrimPrefix(s, "b/")
+}
+
+// CheckStagedSecrets scans the staged changes for possible secrets, returning an
+// error (with the secrets masked in the log) if any are found
+func (u *Updater) checkStagedSecrets() error {
+	out, err := u.runQuiet(u.opts.GitPath, "diff", "--cached", "--no-color", "--no-ext-diff", "--unified=0")
+	if err != nil {
+		return fmt.Errorf("reading staged changes: %w", err)
+	}
+
+	findings := scanSecrets(out, u.secretRules())
+	if len(findings) == 0 {
+		return nil
+	}
+
+	locations := []string{}
+
+	u.println("\n==========\nPossible secrets found in the staged changes:")
+	for _, f := range findings {
+		locations = append(locations, f.String())
+		if f.Masked != "" {
+			u.printf("- %s: %s\n", f, strings.TrimSpace(f.Masked))
+		} else {
+			u.printf("- %s\n", f)
+		}
+	}
+	u.println("==========")
+
+	return fmt.Errorf("possible secrets found, not pushing: %s", strings.Join(locations, ", "))
+}
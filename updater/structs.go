@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Package struct for composer
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  struct {
+		Type      string `json:"type"`
+		URL       string `json:"url"`
+		Reference string `json:"reference"`
+	} `json:"source"`
+	Homepage string    `json:"homepage,omitempty"`
+	Type     string    `json:"type,omitempty"`
+	Require  StringMap `json:"require,omitempty"`
+	// Time is the release date of the version, eg: 2024-01-31T12:00:00+00:00
+	Time string `json:"time,omitempty"`
+}
+
+// ComposerLock struct
+type ComposerLock struct {
+	Checksum    string
+	ContentHash string    `json:"content-hash"`
+	Packages    []Package `json:"packages"`
+	PackagesDev []Package `json:"packages-dev"`
+	// Platform are the platform requirements of composer.json, eg: php
+	Platform          StringMap `json:"platform"`
+	PlatformDev       StringMap `json:"platform-dev"`
+	PlatformOverrides StringMap `json:"platform-overrides,omitempty"`
+}
+
+// StringMap is a JSON object of strings. Composer encodes empty
+// objects as an empty array.
+type StringMap map[string]string
+
+// UnmarshalJSON decodes an object, or an empty array
+func (m *StringMap) UnmarshalJSON(b []byte) error {
+	if string(bytes.TrimSpace(b)) == "[]" {
+		*m = StringMap{}
+		return nil
+	}
+
+	v := map[string]string{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*m = v
+
+	return nil
+}
+
+// ComposerDiffPackage struct
+type ComposerDiffPackage struct {
+	Name        string
+	PreVersion  string
+	PostVersion string //
+	URL         string // url
+	CompareURL  string // url
+}
+
+// ComposerDiff struct
+type ComposerDiff struct {
+	Checksum      string
+	Packages      []ComposerDiffPackage
+	Description   string
+	CommitMessage string
+}
+
+// MRMetadata is embedded in the merge request description as a hidden
+// HTML comment to identify merge requests created by this tool
+type MRMetadata struct {
+	Checksum     string `json:"checksum"`
+	TargetBranch string `json:"target_branch"`
+	Group        string `json:"group,omitempty"`
+	Package      string `json:"package,omitempty"`
+	// FullChecksum is the checksum of the full update when bisecting held back packages
+	FullChecksum string `json:"full_checksum,omitempty"`
+}
+
+// ComposerProblem is a single problem of a composer dependency resolution failure
+type ComposerProblem struct {
+	// Lines are the problem details as reported by composer
+	Lines []string
+	// Packages are all the packages involved in the problem
+	Packages []string
+}
+
+// IssueMetadata is embedded in the failure issue description as a hidden
+// HTML comment to identify issues created by this tool
+type IssueMetadata struct {
+	TargetBranch string `json:"target_branch"`
+	Kind         string `json:"kind,omitempty"`
+}
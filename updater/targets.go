@@ -0,0 +1,203 @@
+package updater
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TargetBranch is a maintained branch updated with its own update policy
+type TargetBranch struct {
+	// Name is the branch name, eg: "release/2.x"
+	Name string `json:"name"`
+	// Policy is the update policy of the branch, see Options.UpdatePolicy.
+	// Defaults to Options.UpdatePolicy if not set.
+	Policy string `json:"policy"`
+}
+
+// BranchResult is the result of a single target branch update
+type BranchResult struct {
+	// Branch is the target branch name
+	Branch string
+	// Policy is the update policy of the branch
+	Policy string
+	// Result is the result of the branch update
+	Result Result
+	// Err is the error updating the branch, if any
+	Err error
+}
+
+// SetBranches sets the target branches from a comma-separated list of branches, each
+// with an optional update policy, eg: "main,release/2.x:security". A single branch
+// sets GitBranch (and the UpdatePolicy if set).
+func (o *Options) SetBranches(list string) {
+	branches := []TargetBranch{}
+	for _, item := range strings.Split(list, ",") {
+		name, policy, _ := strings.Cut(strings.TrimSpace(item), ":")
+		if name != "" {
+			branches = append(branches, TargetBranch{Name: name, Policy: strings.ToLower(policy)})
+		}
+	}
+
+	o.Branches = nil
+
+	switch len(branches) {
+	case 0:
+		o.GitBranch = ""
+	case 1:
+		o.GitBranch = branches[0].Name
+		if branches[0].Policy != "" {
+			o.UpdatePolicy = branches[0].Policy
+		}
+	default:
+		o.GitBranch = branches[0].Name
+		o.Branches = branches
+	}
+}
+
+// BranchPolicy returns the update policy of a target branch
+func (o *Options) branchPolicy(b TargetBranch) string {
+	if b.Policy != "" {
+		return b.Policy
+	}
+
+	return o.UpdatePolicy
+}
+
+// ValidateBranches returns the errors of the update policy & target branches
+func (o *Options) validateBranches() []error {
+	errors := []error{}
+
+	if !slices.Contains(updatePolicies, o.UpdatePolicy) {
+		errors = append(errors, fmt.Errorf("invalid update policy \"%s\"", o.UpdatePolicy))
+	}
+
+	names := map[string]bool{}
+	for _, b := range o.Branches {
+		if b.Name == "" || names[b.Name] {
+			errors = append(errors, fmt.Errorf("invalid target branch \"%s\": branches require a unique name", b.Name))
+		}
+		names[b.Name] = true
+
+		if b.Policy != "" && !slices.Contains(updatePolicies, b.Policy) {
+			errors = append(errors, fmt.Errorf("invalid update policy \"%s\" of branch %s", b.Policy, b.Name))
+		}
+	}
+
+	return errors
+}
+
+// updatePolicies are the supported update policies
+var updatePolicies = []string{"full", "security"}
+
+// RunBranches updates each target branch sequentially with its own update policy,
+// creating (or updating) a merge request per branch. With open merge request limits
+// the new merge requests of all branches are collected first, and the branches of
+// the merge requests allotted in order of priority are then updated again.
+func (u *Updater) runBranches(now time.Time) (Result, error) {
+	result := Result{Status: StatusNoUpdates}
+	failed := []string{}
+
+	// each branch is checked out discarding the changes of the previous update
+	if err := u.checkWorkTree(); err != nil {
+		return result, err
+	}
+
+	if u.limitsEnabled() {
+		u.allotment = &mrAllotment{}
+		defer func() { u.allotment = nil }()
+	}
+
+	for _, b := range u.opts.Branches {
+		result.Branches = append(result.Branches, u.updateBranch(b, now))
+	}
+
+	if u.allotment != nil && len(u.allotment.candidates) > 0 {
+		allotted, err := u.allotMRs(u.allotment.candidates)
+		if err != nil {
+			return result, fmt.Errorf("listing open merge requests: %w", err)
+		}
+		u.allotment.allotted = allotted
+
+		for i, b := range u.opts.Branches {
+			for _, c := range u.allotment.candidates {
+				if c.branch == b.Name && allotted[c.key] {
+					result.Branches[i] = u.updateBranch(b, now)
+					break
+				}
+			}
+		}
+
+		pending := []PendingMR{}
+		for _, br := range result.Branches {
+			pending = append(pending, br.Result.Pending...)
+		}
+
+		if len(pending) > 0 {
+			u.printPending(pending)
+		}
+	}
+
+	statuses := []Status{}
+	for _, br := range result.Branches {
+		statuses = append(statuses, br.Result.Status)
+		if br.Err != nil {
+			failed = append(failed, br.Branch)
+		}
+	}
+	result.Status = combinedStatus(statuses)
+
+	if len(failed) > 0 {
+		return result, fmt.Errorf("updating %s failed", strings.Join(failed, ", "))
+	}
+
+	return result, nil
+}
+
+// UpdateBranch fetches & updates a target branch with its update policy
+func (u *Updater) updateBranch(b TargetBranch, now time.Time) BranchResult {
+	policy := u.opts.branchPolicy(b)
+
+	u.printf("\n==========\nUpdating branch %s (%s update)\n==========\n", b.Name, policy)
+
+	u.opts.GitBranch = b.Name
+	u.mrGroup = ""
+	u.mrPackage = ""
+
+	br := BranchResult{Branch: b.Name, Policy: policy}
+
+	if br.Err = u.fetchBranch(b.Name); br.Err == nil {
+		br.Result, br.Err = u.update(policy, now)
+	}
+
+	if br.Err != nil {
+		u.printf("Error updating branch %s: %s\n", b.Name, br.Err)
+	}
+
+	return br
+}
+
+// SecurityUpdateArgs returns the composer update arguments of the security update
+// policy, updating only the packages with security advisories (with their dependencies)
+func securityUpdateArgs(advisories map[string][]Advisory) []string {
+	names := []string{}
+	for name := range advisories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return append([]string{"--with-dependencies"}, names...)
+}
+
+// CombinedStatus returns the most significant status of multiple merge requests
+func combinedStatus(statuses []Status) Status {
+	for _, status := range []Status{StatusCreated, StatusRegenerated, StatusExists, StatusPending} {
+		if slices.Contains(statuses, status) {
+			return status
+		}
+	}
+
+	return StatusNoUpdates
+}
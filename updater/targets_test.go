@@ -0,0 +1,131 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetBranches(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SetBranches("release/1.x:security")
+	if opts.GitBranch != "release/1.x" || opts.UpdatePolicy != "security" || opts.Branches != nil {
+		t.Errorf("unexpected single branch: %s %s %v", opts.GitBranch, opts.UpdatePolicy, opts.Branches)
+	}
+
+	opts = DefaultOptions()
+	opts.SetBranches("main, release/2.x:Security,")
+	expected := []TargetBranch{{Name: "main"}, {Name: "release/2.x", Policy: "security"}}
+	if opts.GitBranch != "main" || opts.UpdatePolicy != "full" || !reflect.DeepEqual(opts.Branches, expected) {
+		t.Errorf("unexpected branches: %s %s %v", opts.GitBranch, opts.UpdatePolicy, opts.Branches)
+	}
+}
+
+func TestUpdateBranches(t *testing.T) {
+	audit := []string{"audit", "--locked", "--format=json", "--no-interaction"}
+	advisory := `{"advisories": {"vendor/pkg": [{"advisoryId": "PKSA-1", "packageName": "vendor/pkg", "title": "XSS"}]}, "abandoned": []}`
+
+	fetch := func(branch string) []FakeResponse {
+		return []FakeResponse{
+			{Bin: "git", Args: []string{"fetch", "origin", "+refs/heads/" + branch + ":refs/remotes/origin/" + branch}},
+			{Bin: "git", Args: []string{"for-each-ref", "--format=%(objectname)", "refs/heads/" + branch}},
+			{Bin: "git", Args: []string{"checkout", "--force", "-B", branch, "--track", "origin/" + branch}, Do: writeLock(preLock)},
+			{Bin: "git", Args: []string{"checkout", branch}},
+			{Bin: "git", Args: []string{"pull", "--rebase"}},
+		}
+	}
+
+	responses := []FakeResponse{{Bin: "git", Args: []string{"status", "--porcelain", "--untracked-files=no"}}}
+	responses = append(responses, fetch("main")...)
+	responses = append(responses,
+		FakeResponse{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress"}, Do: writeLock(postLock)},
+	)
+	responses = append(responses, gitSetupResponses()...)
+	responses = append(responses, commitResponses("composer-update/main", []string{"vendor/pkg: 1.0.0...1.1.0"})...)
+	responses = append(responses, fetch("release/2.x")...)
+	responses = append(responses,
+		FakeResponse{Bin: "composer-2", Args: audit, Output: advisory, Err: fmt.Errorf("exit status 1")},
+		FakeResponse{Bin: "composer-2", Args: []string{"update", "--no-interaction", "--no-progress", "--with-dependencies", "vendor/pkg"}, Do: writeLock(postLock)},
+	)
+	responses = append(responses, commitResponses("composer-update/release/2.x", []string{"vendor/pkg: 1.0.0...1.1.0"})...)
+	responses = append(responses, fetch("release/1.x")...)
+	responses = append(responses,
+		FakeResponse{Bin: "composer-2", Args: audit, Output: `{"advisories": [], "abandoned": []}`},
+	)
+
+	runner := &FakeRunner{Responses: responses}
+
+	u, server := setup(t, runner, "")
+	u.opts.Branches = []TargetBranch{{Name: "main"}, {Name: "release/2.x", Policy: "security"}, {Name: "release/1.x", Policy: "security"}}
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runner.Unused()) > 0 {
+		t.Errorf("expected all commands to run, unused: %v", runner.Unused())
+	}
+
+	if result.Status != StatusCreated || len(result.Branches) != 3 {
+		t.Fatalf("unexpected result: %v %+v", result.Status, result.Branches)
+	}
+
+	for i, status := range []Status{StatusCreated, StatusCreated, StatusNoUpdates} {
+		if br := result.Branches[i]; br.Result.Status != status || br.Err != nil {
+			t.Errorf("%s: expected %s, got %s (%v)", br.Branch, status, br.Result.Status, br.Err)
+		}
+	}
+
+	mrs := server.OpenMergeRequests()
+	if len(mrs) != 2 {
+		t.Fatalf("expected 2 merge requests, got %d", len(mrs))
+	}
+
+	for _, mr := range mrs {
+		if mr.SourceBranch != "composer-update/"+mr.TargetBranch {
+			t.Errorf("unexpected branches: %s -> %s", mr.SourceBranch, mr.TargetBranch)
+		}
+	}
+}
+
+func TestUpdateBranchesKeepsLocalChanges(t *testing.T) {
+	branches := []TargetBranch{{Name: "main"}, {Name: "release/2.x", Policy: "security"}}
+
+	// uncommitted changes stop the run
+	runner := &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"status", "--porcelain", "--untracked-files=no"}, Output: " M composer.json\n"},
+	}}
+
+	u, _ := setup(t, runner, "")
+	u.opts.Branches = branches
+
+	if _, err := u.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Errorf("expected the uncommitted changes to stop the run, got %v", err)
+	}
+
+	// a branch with unpushed commits is not reset
+	runner = &FakeRunner{Responses: []FakeResponse{
+		{Bin: "git", Args: []string{"status", "--porcelain", "--untracked-files=no"}},
+		{Bin: "git", Args: []string{"fetch", "origin", "+refs/heads/main:refs/remotes/origin/main"}},
+		{Bin: "git", Args: []string{"for-each-ref", "--format=%(objectname)", "refs/heads/main"}, Output: "0123abcd\n"},
+		{Bin: "git", Args: []string{"rev-list", "--count", "refs/remotes/origin/main..refs/heads/main"}, Output: "2\n"},
+		{Bin: "git", Args: []string{"fetch", "origin", "+refs/heads/release/2.x:refs/remotes/origin/release/2.x"}},
+		{Bin: "git", Args: []string{"for-each-ref", "--format=%(objectname)", "refs/heads/release/2.x"}, Output: "4567cdef\n"},
+		{Bin: "git", Args: []string{"rev-list", "--count", "refs/remotes/origin/release/2.x..refs/heads/release/2.x"}, Output: "1\n"},
+	}}
+
+	u, _ = setup(t, runner, "")
+	u.opts.Branches = branches
+
+	result, err := u.Run(context.Background())
+	if err == nil || len(result.Branches) != 2 || result.Branches[0].Err == nil || !strings.Contains(result.Branches[0].Err.Error(), "2 commits are not pushed") {
+		t.Errorf("expected the branches not to be reset, got %v %+v", err, result.Branches)
+	}
+
+	if len(runner.Unused()) > 0 || hasCall(runner.Calls, "git", "checkout") {
+		t.Errorf("expected no checkout, got %v", runner.Calls)
+	}
+}
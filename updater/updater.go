@@ -0,0 +1,536 @@
+// Package updater runs composer updates on a GitLab project repository,
+// creating (or updating) merge requests with the changes
+package updater
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Status is the outcome of an update run
+type Status string
+
+const (
+	// StatusNoUpdates means composer did not update any packages
+	StatusNoUpdates Status = "no-updates"
+	// StatusExists means an identical merge request already exists
+	StatusExists Status = "exists"
+	// StatusRegenerated means an existing merge request was regenerated
+	StatusRegenerated Status = "regenerated"
+	// StatusCreated means a new merge request was created
+	StatusCreated Status = "created"
+	// StatusDisabled means updates are disabled in the repository configuration file
+	StatusDisabled Status = "disabled"
+	// StatusPending means the merge request was not created as the open merge request limit is reached
+	StatusPending Status = "pending"
+	// StatusOutsideSchedule means the run is outside all the schedule rules
+	StatusOutsideSchedule Status = "outside-schedule"
+)
+
+// Result is the result of an update run
+type Result struct {
+	// Status is the outcome of the run
+	Status Status
+	// Checksum is the checksum of the updated composer.lock
+	Checksum string
+	// Packages are the added, updated & removed packages
+	Packages []ComposerDiffPackage
+	// MergeRequest is the created, regenerated or existing merge request
+	MergeRequest *gitlab.MergeRequest
+	// Superseded are the outdated merge requests which were closed
+	Superseded []*gitlab.MergeRequest
+	// Validation are the results of the post-update validation checks
+	Validation []CheckResult
+	// Verification are the results of the verify commands
+	Verification []CheckResult
+	// Hooks are the results of the pre- & post-update hooks
+	Hooks []CheckResult
+	// UnexpectedFiles are the changed files outside the commit paths, which were not committed
+	UnexpectedFiles []string
+	// Issue is the opened or updated issue when composer could not resolve the
+	// dependencies, or packages were held back by the bisect
+	Issue *gitlab.Issue
+	// SkippedGroups are the package groups which were outside their schedule
+	SkippedGroups []SkippedGroup
+	// HeldBack are the recent releases held back by the minimum release age
+	HeldBack []HeldBackPackage
+	// Bisection is the result of the bisect when the bisect command failed
+	Bisection *Bisection
+	// Pending are the merge requests not created as the open merge request limit is reached
+	Pending []PendingMR
+	// PackageResults are the results of each package merge request in package mode
+	PackageResults []PackageResult
+	// Branches are the results of each target branch when updating multiple branches
+	Branches []BranchResult
+}
+
+// Updater runs the composer update & merge request flow for a single repository
+type Updater struct {
+	opts Options
+
+	// ComposerLockFile file path
+	composerLockFile string
+	// MRBranch is the branch name for the merge request
+	mrBranch string
+	// MRGroup is the package group of the merge request, if all its packages belong to one
+	mrGroup string
+	// MRPackage is the package of the merge request in package mode
+	mrPackage string
+	// Holds are the locked versions of the held back packages (name => version),
+	// which partial updates must not update as dependencies
+	holds map[string]string
+	// FullChecksum is the checksum of the full update when bisecting held back packages
+	fullChecksum string
+	// Allotment decides the new merge requests of multiple target branches within
+	// the open merge request limits, nil for a single branch
+	allotment *mrAllotment
+
+	ctx            context.Context
+	client         *gitlab.Client
+	gitIsSetup     bool
+	projectMembers []*gitlab.ProjectMember
+}
+
+// New returns an Updater for the given options
+func New(opts Options) *Updater {
+	// the output & runner are used before the options are validated
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	if opts.Runner == nil {
+		opts.Runner = ExecRunner{}
+	}
+
+	return &Updater{opts: opts}
+}
+
+// Run runs the full update & merge request flow. The context can be
+// used to cancel both external commands & GitLab API requests.
+func (u *Updater) Run(ctx context.Context) (Result, error) {
+	result := Result{}
+
+	u.ctx = ctx
+
+	config, err := u.loadConfig()
+	if err != nil {
+		return result, fmt.Errorf("reading config: %w", err)
+	}
+
+	if !config.IsEnabled() {
+		u.println("\n==========\nUpdates are disabled in the repository configuration\n==========")
+		result.Status = StatusDisabled
+		return result, nil
+	}
+
+	if err := u.init(); err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+
+	if ok, _ := scheduleMatches(u.opts.Schedule, now); !ok {
+		u.printf("\n==========\nOutside the update schedule (%s)\n==========\n", strings.Join(u.opts.Schedule, "; "))
+		result.Status = StatusOutsideSchedule
+		return result, nil
+	}
+
+	if len(u.opts.Branches) > 0 {
+		return u.runBranches(now)
+	}
+
+	return u.update(u.opts.UpdatePolicy, now)
+}
+
+// Update runs the update & merge request flow of the source branch with the update policy
+func (u *Updater) update(policy string, now time.Time) (Result, error) {
+	result := Result{}
+	u.holds = map[string]string{}
+	u.fullChecksum = ""
+
+	if err := u.switchBranch(u.opts.GitBranch); err != nil {
+		return result, fmt.Errorf("switching branch: %w", err)
+	}
+
+	preUpdate, err := u.parseComposerLock()
+	if err != nil {
+		return result, fmt.Errorf("parsing composer.lock: %w", err)
+	}
+
+	advisories := map[string][]Advisory{}
+	if policy == "security" {
+		if advisories, err = u.audit(); err != nil {
+			return result, fmt.Errorf("auditing packages: %w", err)
+		}
+
+		if len(advisories) == 0 {
+			u.println("\n==========\nThere are no security advisories for the locked packages\n==========")
+			result.Status = StatusNoUpdates
+			return result, nil
+		}
+	} else if u.limitsEnabled() {
+		// security updates are prioritised
+		if advisories, err = u.audit(); err != nil {
+			u.println("Error auditing packages, security updates are not prioritised:", err)
+		}
+	}
+
+	updateArgs := []string{}
+	if policy == "security" {
+		updateArgs = securityUpdateArgs(advisories)
+	}
+
+	if result.Hooks, err = u.runHooks("pre-update", u.opts.PreUpdate); err != nil {
+		return result, err
+	}
+
+	// partial updates start from the lock file as changed by the pre-update hooks
+	original, err := os.ReadFile(u.composerLockFile)
+	if err != nil {
+		return result, fmt.Errorf("reading composer.lock: %w", err)
+	}
+
+	if out, err := u.composerUpdate(updateArgs...); err != nil {
+		if problems := parseComposerProblems(out); len(problems) > 0 && u.opts.FailureIssue {
+			issue, issueErr := u.reportFailure(problems, out)
+			if issueErr != nil {
+				u.println("Error reporting failure:", issueErr)
+			}
+			result.Issue = issue
+		}
+
+		return result, fmt.Errorf("updating with composer: %w", err)
+	}
+
+	if u.opts.FailureIssue {
+		if err := u.closeFailureIssue(); err != nil {
+			u.println("Error closing failure issue:", err)
+		}
+	}
+
+	postUpdate, err := u.parseComposerLock()
+	if err != nil {
+		return result, fmt.Errorf("parsing composer.lock: %w", err)
+	}
+
+	if len(u.opts.Groups) > 0 && preUpdate.Checksum != postUpdate.Checksum {
+		if result.SkippedGroups, err = u.skipGroups(original, preUpdate, postUpdate, now); err != nil {
+			return result, fmt.Errorf("skipping groups: %w", err)
+		}
+
+		if postUpdate, err = u.parseComposerLock(); err != nil {
+			return result, fmt.Errorf("parsing composer.lock: %w", err)
+		}
+	}
+
+	if u.opts.MinReleaseAge > 0 && preUpdate.Checksum != postUpdate.Checksum {
+		if result.HeldBack, err = u.holdBackRecentReleases(original, preUpdate, postUpdate); err != nil {
+			return result, fmt.Errorf("holding back recent releases: %w", err)
+		}
+
+		if postUpdate, err = u.parseComposerLock(); err != nil {
+			return result, fmt.Errorf("parsing composer.lock: %w", err)
+		}
+	}
+
+	// check if composer lock has been modified
+	if preUpdate.Checksum == postUpdate.Checksum {
+		u.println("\n==========\nThere are no updated composer modules\n==========")
+		for _, p := range result.HeldBack {
+			u.printf("%s %s is held back until %s\n", p.Name, p.Version, p.Eligible.UTC().Format("2006-01-02 15:04"))
+		}
+		result.Status = StatusNoUpdates
+		return result, nil
+	}
+
+	if u.opts.MRMode == "package" {
+		return result, u.runPackages(original, preUpdate, postUpdate, advisories, &result)
+	}
+
+	diff, err := u.describeUpdate(preUpdate, postUpdate, &result)
+	if err != nil {
+		return result, err
+	}
+
+	existing, reason := u.existingMR(diff.Checksum, &result)
+	if existing != nil && reason == "" {
+		return result, nil
+	}
+
+	if u.opts.Bisect != "" {
+		u.printf("Testing: %s\n", u.opts.Bisect)
+
+		if r := u.commandCheck(u.opts.Bisect); r.Passed {
+			if u.opts.FailureIssue {
+				if err := u.closeIssue(issueKindBisect, fmt.Sprintf("All updates of `%s` pass `%s`", u.opts.GitBranch, u.opts.Bisect)); err != nil {
+					u.println("Error closing bisect issue:", err)
+				}
+			}
+		} else {
+			bisection, err := u.bisect(original, diff, r)
+			if err != nil {
+				return result, fmt.Errorf("bisecting: %w", err)
+			}
+			result.Bisection = &bisection
+			// the full update is not bisected again while the merge request is open
+			u.fullChecksum = diff.Checksum
+
+			if u.opts.FailureIssue {
+				issue, err := u.reportBisection(bisection)
+				if err != nil {
+					u.println("Error reporting held back packages:", err)
+				}
+				result.Issue = issue
+			}
+
+			if postUpdate, err = u.parseComposerLock(); err != nil {
+				return result, fmt.Errorf("parsing composer.lock: %w", err)
+			}
+
+			if preUpdate.Checksum == postUpdate.Checksum {
+				u.println("\n==========\nThere are no updated composer modules after holding back the failing packages\n==========")
+				result.Status = StatusNoUpdates
+				return result, nil
+			}
+
+			if diff, err = u.describeUpdate(preUpdate, postUpdate, &result); err != nil {
+				return result, err
+			}
+
+			existing, reason = u.existingMR(diff.Checksum, &result)
+			if existing != nil && reason == "" {
+				return result, nil
+			}
+		}
+	}
+	diff.Description += skippedGroupsReport(result.SkippedGroups)
+	diff.Description += u.heldBackReport(result.HeldBack)
+	diff.Description += bisectReport(result.Bisection)
+
+	packages := "package"
+	if len(diff.Packages) > 0 {
+		packages = "packages"
+	}
+
+	mrTitle := fmt.Sprintf("%s %d %s", u.opts.MRTitlePrefix, len(diff.Packages), packages)
+
+	u.mrGroup = u.diffGroup(diff.Packages)
+	u.mrBranch = u.branchName(diff.Checksum, "")
+
+	var oldMRs []*gitlab.MergeRequest
+	if existing == nil {
+		// the open merge request of a stable branch is updated in place
+		if existing = u.branchMR(u.mrBranch); existing != nil {
+			reason = "a newer update is available"
+			result.MergeRequest = existing
+		}
+
+		if oldMRs, err = u.oldMRs(); err != nil {
+			return result, fmt.Errorf("listing old merge requests: %w", err)
+		}
+
+		oldMRs = slices.DeleteFunc(oldMRs, func(mr *gitlab.MergeRequest) bool {
+			return existing != nil && mr.IID == existing.IID
+		})
+
+		if existing == nil && u.limitsEnabled() {
+			pending := PendingMR{
+				Title:    mrTitle,
+				Group:    u.mrGroup,
+				Priority: updatePriority(diff.Packages, advisories),
+				Packages: diff.Packages,
+			}
+
+			allowed, decided := u.allotMR(pending, oldMRs)
+			if !decided {
+				limiter, err := u.newMRLimiter(oldMRs)
+				if err != nil {
+					return result, fmt.Errorf("listing open merge requests: %w", err)
+				}
+				allowed = limiter.allow(u.mrGroup)
+			}
+
+			if !allowed {
+				result.Pending = append(result.Pending, pending)
+				if u.allotment == nil {
+					u.printPending(result.Pending)
+				}
+				result.Status = StatusPending
+				return result, nil
+			}
+		}
+	}
+
+	hooks, err := u.runHooks("post-update", u.opts.PostUpdate)
+	result.Hooks = append(result.Hooks, hooks...)
+	if err != nil {
+		return result, err
+	}
+	diff.Description += hooksReport(result.Hooks)
+
+	result.Verification = u.runVerification()
+	if failed := failedChecks(result.Verification); len(failed) > 0 {
+		if u.opts.VerifyPolicy == "skip" {
+			return result, fmt.Errorf("verification failed, merge request skipped: %s", strings.Join(failed, ", "))
+		}
+		mrTitle = "Draft: " + mrTitle
+	}
+	diff.Description += checksReport("Verification", result.Verification)
+	diff.Description += supersededReport(oldMRs)
+
+	files, err := u.commitFiles(&diff, &result)
+	if err != nil {
+		return result, err
+	}
+
+	if existing != nil {
+		u.printf("Regenerating merge request !%d: %s\n", existing.IID, reason)
+
+		if err := u.updateMergeBranch(diff, existing.SourceBranch, files); err != nil {
+			return result, fmt.Errorf("updating merge request branch: %w", err)
+		}
+
+		if err := u.updateMergeRequest(existing, mrTitle, diff.Description, diff.Checksum, reason); err != nil {
+			return result, err
+		}
+
+		result.Status = StatusRegenerated
+
+		if err := u.closeOldMRs(oldMRs, existing); err != nil {
+			return result, fmt.Errorf("closing old merge requests: %w", err)
+		}
+
+		result.Superseded = oldMRs
+
+		return result, nil
+	}
+
+	// the branch name is stable, so replace any stale remote branch
+	if err := u.updateMergeBranch(diff, u.mrBranch, files); err != nil {
+		return result, fmt.Errorf("creating merge request: %w", err)
+	}
+
+	mr, err := u.createMergeRequest(mrTitle, diff.Description, diff.Checksum)
+	if err != nil {
+		return result, err
+	}
+
+	result.Status = StatusCreated
+	result.MergeRequest = mr
+
+	if err := u.closeOldMRs(oldMRs, mr); err != nil {
+		return result, fmt.Errorf("closing old merge requests: %w", err)
+	}
+
+	result.Superseded = oldMRs
+
+	return result, nil
+}
+
+// DescribeUpdate compares the lock files & runs the validation checks,
+// returning the diff with the merge request description
+func (u *Updater) describeUpdate(pre, post ComposerLock, result *Result) (ComposerDiff, error) {
+	diff := u.compareDiffs(pre, post)
+	diff.Description += u.phpCompatibilityReport(post)
+
+	result.Checksum = diff.Checksum
+	result.Packages = diff.Packages
+
+	result.Validation = u.runValidation(post)
+	if failed := failedChecks(result.Validation); len(failed) > 0 && u.opts.ValidatePolicy == "abort" {
+		return diff, fmt.Errorf("validation failed: %s", strings.Join(failed, ", "))
+	}
+	diff.Description += checksReport("Validation", result.Validation)
+
+	return diff, nil
+}
+
+// ExistingMR returns the existing merge request with the checksum (if any), and
+// the reason to regenerate it. An identical merge request sets the result status.
+func (u *Updater) existingMR(checksum string, result *Result) (*gitlab.MergeRequest, string) {
+	existing := u.findMR(checksum)
+	result.MergeRequest = existing
+
+	if existing == nil {
+		return nil, ""
+	}
+
+	reason := u.regenerateReason(existing)
+	if reason == "" {
+		u.printf("\n==========\nAn identical merge request already exists with checksum: %s\n==========\n", checksum)
+		result.Status = StatusExists
+	}
+
+	return existing, reason
+}
+
+// Init validates the options & sets up the API client
+func (u *Updater) init() error {
+	if u.ctx == nil {
+		u.ctx = context.Background()
+	}
+
+	if err := u.opts.validate(); err != nil {
+		return err
+	}
+
+	errors := []error{}
+
+	if err := u.detectProject(); err != nil {
+		errors = append(errors, err)
+	}
+
+	if u.opts.ProjectID == "" {
+		u.opts.ProjectID = u.opts.ProjectPath
+	}
+	if u.opts.ProjectPath == "" {
+		u.opts.ProjectPath = u.opts.ProjectID
+	}
+
+	if u.opts.APIURL == "" || u.opts.ProjectID == "" {
+		return &ConfigError{Errors: append(errors, fmt.Errorf("gitlab API URL & project not set, and could not be detected from the git remote"))}
+	}
+
+	u.composerLockFile = path.Join(u.opts.RepoDir, "composer.lock")
+	if !isFile(u.composerLockFile) {
+		errors = append(errors, fmt.Errorf("%s not found", u.composerLockFile))
+	}
+
+	client, err := gitlab.NewClient(u.opts.Token,
+		gitlab.WithBaseURL(u.opts.APIURL),
+		gitlab.WithRequestOptions(gitlab.WithContext(u.ctx)),
+	)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to create client: %v", err))
+	}
+	u.client = client
+
+	if len(errors) == 0 {
+		// test if project's merge requests are accessible
+		if !u.isMREnabled() {
+			errors = append(errors, fmt.Errorf("merge requests not enabled for %s, or API user doesn't have access to project", u.opts.ProjectPath))
+		}
+	}
+
+	if len(errors) > 0 {
+		return &ConfigError{Errors: errors}
+	}
+
+	return nil
+}
+
+// Printf writes formatted progress output
+func (u *Updater) printf(format string, a ...interface{}) {
+	fmt.Fprintf(u.opts.Output, format, a...)
+}
+
+// Println writes progress output
+func (u *Updater) println(a ...interface{}) {
+	fmt.Fprintln(u.opts.Output, a...)
+}
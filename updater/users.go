@@ -0,0 +1,259 @@
+package updater
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// GetProjectMembers returns all members of the project, including those
+// inherited from parent groups. Results are cached for the duration of the run.
+func (u *Updater) getProjectMembers() ([]*gitlab.ProjectMember, error) {
+	if u.projectMembers != nil {
+		return u.projectMembers, nil
+	}
+
+	members := []*gitlab.ProjectMember{}
+
+	opts := gitlab.ListProjectMembersOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+
+	for {
+		page, resp, err := u.client.ProjectMembers.ListAllProjectMembers(u.opts.ProjectID, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, page...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	u.projectMembers = members
+
+	return u.projectMembers, nil
+}
+
+// GetGroupMemberIDs returns the IDs of all members of a group (including inherited members)
+func (u *Updater) getGroupMemberIDs(group string) ([]int, error) {
+	ids := []int{}
+
+	opts := gitlab.ListGroupMembersOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+
+	for {
+		members, resp, err := u.client.Groups.ListAllGroupMembers(group, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range members {
+			ids = append(ids, m.ID)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	return ids, nil
+}
+
+// ResolveUsers converts a list of user references into GitLab user IDs.
+// References can be usernames (optionally prefixed with "@"), email addresses,
+// numeric user IDs, or "@group" paths which are expanded to all the group's members.
+// Duplicates are removed and any unresolved references are reported as a warning.
+func (u *Updater) resolveUsers(refs []string) []int {
+	ids := []int{}
+	seen := make(map[int]bool)
+
+	add := func(id int) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	members, err := u.getProjectMembers()
+	if err != nil {
+		u.println("Error listing project members: ", err)
+		members = []*gitlab.ProjectMember{}
+	}
+
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+
+		found, err := u.resolveUser(ref, members)
+		if err != nil || len(found) == 0 {
+			u.printf("Warning: unable to resolve user or group \"%s\"\n", ref)
+			continue
+		}
+
+		for _, id := range found {
+			add(id)
+		}
+	}
+
+	return ids
+}
+
+// ResolveUser returns the user ID(s) for a single reference
+func (u *Updater) resolveUser(ref string, members []*gitlab.ProjectMember) ([]int, error) {
+	lower := strings.ToLower(ref)
+
+	// numeric user ID
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, m := range members {
+			if m.ID == id {
+				return []int{id}, nil
+			}
+		}
+
+		return u.lookupUserID(id)
+	}
+
+	// @username or @group/subgroup
+	if strings.HasPrefix(lower, "@") {
+		name := strings.TrimPrefix(lower, "@")
+
+		if !strings.Contains(name, "/") {
+			for _, m := range members {
+				if strings.ToLower(m.Username) == name {
+					return []int{m.ID}, nil
+				}
+			}
+
+			if ids, err := u.lookupUsername(name); err == nil && len(ids) > 0 {
+				return ids, nil
+			}
+		}
+
+		return u.getGroupMemberIDs(name)
+	}
+
+	// email address
+	if strings.Contains(lower, "@") {
+		for _, m := range members {
+			if strings.ToLower(m.Email) == lower {
+				return []int{m.ID}, nil
+			}
+		}
+
+		return u.lookupEmail(lower)
+	}
+
+	// plain username
+	for _, m := range members {
+		if strings.ToLower(m.Username) == lower {
+			return []int{m.ID}, nil
+		}
+	}
+
+	return u.lookupUsername(lower)
+}
+
+// LookupUserID confirms a user ID exists
+func (u *Updater) lookupUserID(id int) ([]int, error) {
+	user, _, err := u.client.Users.GetUser(id, gitlab.GetUsersOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return []int{user.ID}, nil
+}
+
+// LookupUsername returns the ID of a user by their username
+func (u *Updater) lookupUsername(username string) ([]int, error) {
+	users, _, err := u.client.Users.ListUsers(&gitlab.ListUsersOptions{
+		Username: gitlab.Ptr(username),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		if strings.ToLower(u.Username) == username {
+			return []int{u.ID}, nil
+		}
+	}
+
+	return []int{}, nil
+}
+
+// LookupEmail returns the ID of a user by their (public) email address
+func (u *Updater) lookupEmail(email string) ([]int, error) {
+	users, _, err := u.client.Users.ListUsers(&gitlab.ListUsersOptions{
+		Search: gitlab.Ptr(email),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the search also matches partial names, usernames & email addresses
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) || strings.EqualFold(user.PublicEmail, email) {
+			return []int{user.ID}, nil
+		}
+	}
+
+	u.printf("No user found with the public email address %s\n", email)
+
+	return []int{}, nil
+}
+
+// RotateReviewer returns a single reviewer from the list, selected round-robin.
+// The previous selection is persisted between runs by looking up the reviewer
+// of the most recent composer-update merge request created by the API user.
+func (u *Updater) rotateReviewer(ids []int) []int {
+	if len(ids) < 2 {
+		return ids
+	}
+
+	opts := gitlab.ListProjectMergeRequestsOptions{
+		State:        gitlab.Ptr("all"),
+		TargetBranch: gitlab.Ptr(u.opts.GitBranch),
+		OrderBy:      gitlab.Ptr("created_at"),
+		Sort:         gitlab.Ptr("desc"),
+		ListOptions:  gitlab.ListOptions{PerPage: 20},
+	}
+
+	me, _, err := u.client.Users.CurrentUser()
+	if err == nil {
+		opts.AuthorID = &me.ID
+	}
+
+	mrs, _, err := u.client.MergeRequests.ListProjectMergeRequests(u.opts.ProjectID, &opts)
+	if err != nil {
+		u.println("Error listing MRs: ", err)
+		return ids[:1]
+	}
+
+	for _, mr := range mrs {
+		if _, ok := u.parseMRMetadata(mr); !ok || len(mr.Reviewers) == 0 {
+			continue
+		}
+
+		for i, id := range ids {
+			if mr.Reviewers[0].ID == id {
+				return []int{ids[(i+1)%len(ids)]}
+			}
+		}
+
+		// the previous reviewer is no longer in the list
+		break
+	}
+
+	return ids[:1]
+}
@@ -1,4 +1,4 @@
-package app
+package updater
 
 import (
 	"crypto/sha256"
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // EnvString will return an environment variable, else a default
@@ -31,6 +32,33 @@ func envInt(key string, defaultValue int) int {
 	return val
 }
 
+// EnvCount will return an environment int where 0 is a valid value (eg: no retries), else a default
+func envCount(key string, defaultValue int) int {
+	val := defaultValue
+	if os.Getenv(key) != "" {
+		i, err := strconv.Atoi(os.Getenv(key))
+		if err == nil && i >= 0 {
+			val = i
+		}
+	}
+	return val
+}
+
+// EnvDuration will return an environment duration (eg: "10m" or seconds), else a default
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return defaultValue
+	}
+	if i, err := strconv.Atoi(val); err == nil && i >= 0 {
+		return time.Duration(i) * time.Second
+	}
+	if d, err := time.ParseDuration(val); err == nil && d >= 0 {
+		return d
+	}
+	return defaultValue
+}
+
 // EnvCSVSlice will return an environment CSV slice, else a default
 func envCSVSlice(key string, defaultValues []string) []string {
 	if os.Getenv(key) != "" {
@@ -39,6 +67,35 @@ func envCSVSlice(key string, defaultValues []string) []string {
 	return defaultValues
 }
 
+// TrimSlice returns the non-empty values with the surrounding spaces removed
+func trimSlice(values []string) []string {
+	trimmed := []string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			trimmed = append(trimmed, v)
+		}
+	}
+
+	return trimmed
+}
+
+// EnvLines will return the non-empty lines of an environment variable, else a default.
+// Used for commands which may contain commas.
+func envLines(key string, defaultValues []string) []string {
+	if os.Getenv(key) == "" {
+		return defaultValues
+	}
+
+	lines := []string{}
+	for _, l := range strings.Split(os.Getenv(key), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	return lines
+}
+
 // EnvTrue will return an environment boolean, else a default
 func envTrue(key string, defaultValue bool) bool {
 	if os.Getenv(key) != "" {
@@ -70,6 +127,11 @@ func isFile(path string) bool {
 	return true
 }
 
+// AbsPath returns the absolute path
+func absPath(p string) (string, error) {
+	return filepath.Abs(p)
+}
+
 // Which locates a binary in the current $PATH.
 func which(binName string) (string, error) {
 	return exec.LookPath(binName)